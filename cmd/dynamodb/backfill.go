@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gopkg.in/Clever/kayvee-go.v6/logger"
+
+	"github.com/Clever/ddb-to-es/es"
+	"github.com/Clever/ddb-to-es/export"
+)
+
+// backfillBatchSize is the number of items converted and written at once
+const backfillBatchSize = 1000
+
+// Checkpoint records the progress of a backfill, so that an interrupted run resumes where it stopped
+type Checkpoint struct {
+	// Export is the URL of the export being backfilled
+	Export string `json:"export"`
+	// Files holds the progress of each data file started, by name
+	Files map[string]FileProgress `json:"files"`
+}
+
+// FileProgress is the progress of a data file
+type FileProgress struct {
+	// Items is the number of items written, in the order they are in the file
+	Items int  `json:"items"`
+	Done  bool `json:"done"`
+}
+
+// backfill writes the items of a DynamoDB table export to Elasticsearch, returning the process exit code
+func backfill(args []string) int {
+	flags := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	checkpointPath := flags.String("checkpoint", "backfill-checkpoint.json", "file recording progress, to resume from")
+	endpoint := flags.String("endpoint", "", "endpoint of an S3 compatible service")
+	keys := flags.String("keys", strings.Join(Conf.Documents.ID.Keys, ","), "the table's key attributes, comma separated")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: backfill [flags] <export directory or s3://bucket/prefix/AWSDynamoDB/export-id>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		if err == nil {
+			flags.Usage()
+		}
+		return 2
+	}
+	if *keys == "" {
+		fmt.Fprintln(os.Stderr, "backfill needs the table's key attributes: set documents.id.keys or -keys")
+		return 2
+	}
+
+	exportURL := flags.Arg(0)
+	files, err := export.Open(exportURL, *endpoint)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	// exported items are written as they were when exported, rather than as changes, so cutover
+	// windows and change history don't apply
+	Conf.Cutover = CutoverConfig{}
+	Conf.Elasticsearch.HistoryIndex = ""
+
+	if err := backfillExport(DBClient, files, exportURL, strings.Split(*keys, ","), *checkpointPath); err != nil {
+		log.ErrorD("backfill-failed", logger.M{
+			"export": exportURL,
+			"error":  err.Error(),
+		})
+		return 1
+	}
+	return 0
+}
+
+// backfillExport writes the items of the export that the checkpoint doesn't record as written,
+// saving the checkpoint after each batch
+func backfillExport(db es.DB, files export.Files, exportURL string, keys []string, checkpointPath string) error {
+	manifest, err := export.ReadManifest(files)
+	if err != nil {
+		return err
+	}
+	checkpoint, err := loadCheckpoint(checkpointPath, exportURL)
+	if err != nil {
+		return err
+	}
+
+	items := 0
+	for _, dataFile := range manifest.DataFiles {
+		progress := checkpoint.Files[dataFile.Name]
+		if progress.Done {
+			continue
+		}
+		save := func(progress FileProgress) error {
+			checkpoint.Files[dataFile.Name] = progress
+			return saveCheckpoint(checkpointPath, checkpoint)
+		}
+		written, err := backfillFile(db, files, manifest, dataFile, keys, progress, save)
+		items += written
+		if err != nil {
+			return fmt.Errorf("%s: %s", dataFile.Name, err)
+		}
+	}
+
+	log.InfoD("backfill-success", logger.M{
+		"export":     exportURL,
+		"items":      items,
+		"data-files": len(manifest.DataFiles),
+	})
+	return nil
+}
+
+// backfillFile writes the items of a data file after those already written, calling save with its
+// progress after each batch. It returns the number of items written.
+func backfillFile(db es.DB, files export.Files, manifest export.Manifest, dataFile export.DataFile, keys []string,
+	progress FileProgress, save func(FileProgress) error) (int, error) {
+	f, err := files.Open(dataFile.Name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	decoder, err := export.NewDecoder(f, manifest.OutputFormat)
+	if err != nil {
+		return 0, err
+	}
+
+	written := 0
+	records := []events.DynamoDBEventRecord{}
+	for n := 0; ; n++ {
+		item, err := decoder.Decode()
+		if err != nil && err != io.EOF {
+			return written, err
+		}
+		if err == nil && n >= progress.Items {
+			records = append(records, toBackfillRecord(item, keys, manifest.ExportTime, fmt.Sprintf("%s:%d", dataFile.Name, n)))
+		}
+		if len(records) == backfillBatchSize || (err == io.EOF && len(records) > 0) {
+			if _, err := processRecords(records, createDB{db}); err != nil && err != ErrAllRecordsSkipped {
+				return written, err
+			}
+			written += len(records)
+			progress.Items += len(records)
+			records = records[:0]
+			if err := save(progress); err != nil {
+				return written, err
+			}
+		}
+		if err == io.EOF {
+			progress.Done = true
+			return written, save(progress)
+		}
+	}
+}
+
+// createDB only creates the documents written, so that exported items never replace documents the
+// stream wrote since the export. Versioned documents are ordered by their version instead.
+type createDB struct {
+	es.DB
+}
+
+// WriteDocs implements es.DB
+func (db createDB) WriteDocs(docs []es.Doc) (es.WriteResult, error) {
+	for i := range docs {
+		docs[i].Create = true
+	}
+	return db.DB.WriteDocs(docs)
+}
+
+// toBackfillRecord converts an exported item to the stream record that would have inserted it, so
+// that it's converted the same way as items changed since
+func toBackfillRecord(item map[string]events.DynamoDBAttributeValue, keys []string, exportTime time.Time, id string) events.DynamoDBEventRecord {
+	keyValues := map[string]events.DynamoDBAttributeValue{}
+	for _, key := range keys {
+		if value, ok := item[key]; ok {
+			keyValues[key] = value
+		}
+	}
+	return events.DynamoDBEventRecord{
+		EventID:   "backfill:" + id,
+		EventName: string(events.DynamoDBOperationTypeInsert),
+		Change: events.DynamoDBStreamRecord{
+			ApproximateCreationDateTime: events.SecondsEpochTime{Time: exportTime},
+			Keys:                        keyValues,
+			NewImage:                    item,
+			SequenceNumber:              "1",
+			StreamViewType:              "NEW_IMAGE",
+		},
+	}
+}
+
+// loadCheckpoint reads the checkpoint at path, or returns an empty one if it doesn't exist
+func loadCheckpoint(path, exportURL string) (Checkpoint, error) {
+	checkpoint := Checkpoint{Export: exportURL, Files: map[string]FileProgress{}}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return checkpoint, nil
+	} else if err != nil {
+		return Checkpoint{}, fmt.Errorf("could not read checkpoint: %s", err)
+	}
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return Checkpoint{}, fmt.Errorf("invalid checkpoint %s: %s", path, err)
+	}
+	if checkpoint.Export != exportURL {
+		return Checkpoint{}, fmt.Errorf("checkpoint %s is of export %s", path, checkpoint.Export)
+	}
+	if checkpoint.Files == nil {
+		checkpoint.Files = map[string]FileProgress{}
+	}
+	return checkpoint, nil
+}
+
+// saveCheckpoint replaces the checkpoint at path, so that it's never left partially written
+func saveCheckpoint(path string, checkpoint Checkpoint) error {
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("could not save checkpoint: %s", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("could not save checkpoint: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Clever/ddb-to-es/es"
+	"github.com/Clever/ddb-to-es/export"
+)
+
+// recordingDB records the IDs of the docs written, and whether each was only created, failing every
+// batch once failAfter batches are written
+type recordingDB struct {
+	ids       []string
+	creates   []bool
+	batches   int
+	failAfter int
+}
+
+func (db *recordingDB) WriteDocs(docs []es.Doc) (es.WriteResult, error) {
+	if db.failAfter > 0 && db.batches >= db.failAfter {
+		return es.WriteResult{}, errors.New("cluster unavailable")
+	}
+	db.batches++
+	result := es.WriteResult{}
+	for _, doc := range docs {
+		db.ids = append(db.ids, doc.ID)
+		db.creates = append(db.creates, doc.Create)
+		result.Docs = append(result.Docs, es.DocResult{ID: doc.ID, Indices: []es.IndexResult{{Index: "test-index", Status: 201}}})
+	}
+	return result, nil
+}
+
+// writeBackfillExport writes an uncompressed DynamoDB JSON export to a temporary directory
+func writeBackfillExport(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "data"), 0755))
+	files := map[string]string{
+		"manifest-summary.json": `{"exportTime":"2024-07-16T15:00:00Z","outputFormat":"DYNAMODB_JSON","itemCount":3}`,
+		"manifest-files.json": `{"itemCount":2,"dataFileS3Key":"AWSDynamoDB/01234567890123-abcdefgh/data/a.json.gz"}
+{"itemCount":1,"dataFileS3Key":"AWSDynamoDB/01234567890123-abcdefgh/data/b.json.gz"}`,
+		"data/a.json.gz": `{"Item":{"id":{"S":"1"},"val":{"N":"1"}}}
+{"Item":{"id":{"S":"2"},"val":{"N":"2"}}}`,
+		"data/b.json.gz": `{"Item":{"id":{"S":"3"},"val":{"N":"3"}}}`,
+	}
+	for name, data := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644))
+	}
+	return dir
+}
+
+func TestToBackfillRecord(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	Conf.Documents.ID.Keys = []string{"id"}
+	Conf.Documents.VersionSource = VersionSourceCreationTime
+	dir := writeBackfillExport(t)
+
+	decoded := decodeFirst(t, dir)
+	exported := time.Date(2024, 7, 16, 15, 0, 0, 0, time.UTC)
+	doc, ok, err := toDoc(toBackfillRecord(decoded, []string{"id"}, exported, "data/a.json.gz:0"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, es.Doc{
+		Op:        es.OpTypeInsert,
+		ID:        "1",
+		Item:      map[string]interface{}{"id": "1", "val": int64(1)},
+		Version:   exported.UnixNano() / 1e6,
+		Timestamp: exported,
+	}, doc)
+}
+
+func decodeFirst(t *testing.T, dir string) map[string]events.DynamoDBAttributeValue {
+	f, err := os.Open(filepath.Join(dir, "data", "a.json.gz"))
+	require.NoError(t, err)
+	defer f.Close()
+	decoder, err := export.NewDecoder(f, export.FormatDynamoDBJSON)
+	require.NoError(t, err)
+	item, err := decoder.Decode()
+	require.NoError(t, err)
+	return item
+}
+
+func TestBackfillExportResumes(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	Conf.Documents.ID.Keys = []string{"id"}
+	dir := writeBackfillExport(t)
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	// the first data file is written before the cluster becomes unavailable
+	db := &recordingDB{failAfter: 1}
+	err := backfillExport(db, export.DirFiles(dir), dir, []string{"id"}, checkpointPath)
+	assert.EqualError(t, err, "data/b.json.gz: record 0: cluster unavailable")
+	assert.Equal(t, []string{"1", "2"}, db.ids)
+	// documents the stream wrote since the export are kept
+	assert.Equal(t, []bool{true, true}, db.creates)
+
+	checkpoint, err := loadCheckpoint(checkpointPath, dir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]FileProgress{"data/a.json.gz": {Items: 2, Done: true}}, checkpoint.Files)
+
+	db = &recordingDB{}
+	require.NoError(t, backfillExport(db, export.DirFiles(dir), dir, []string{"id"}, checkpointPath))
+	assert.Equal(t, []string{"3"}, db.ids)
+
+	_, err = loadCheckpoint(checkpointPath, "s3://bucket/other")
+	assert.EqualError(t, err, "checkpoint "+checkpointPath+" is of export "+dir)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// How DynamoDB binary attributes are indexed
+const (
+	// BinaryDrop doesn't index binary attributes
+	BinaryDrop = "drop"
+	// BinaryBase64 indexes binary attributes as base64 strings
+	BinaryBase64 = "base64"
+	// BinaryHex indexes binary attributes as hex strings
+	BinaryHex = "hex"
+	// BinarySHA256 indexes the hex SHA-256 digest of binary attributes, so they can be matched exactly
+	BinarySHA256 = "sha256"
+	// BinarySize indexes only the size of binary attributes, as {"size": bytes}
+	BinarySize = "size"
+)
+
+// binaryMode returns how the binary attribute at path is indexed
+func binaryMode(path string) string {
+	return mode(Conf.Documents.Binary.Overrides, Conf.Documents.Binary.Default, path)
+}
+
+// toBinary converts a DynamoDB binary attribute at path as configured, returning nil if it is dropped
+func toBinary(b []byte, path string) interface{} {
+	return encodeBinary(b, binaryMode(path))
+}
+
+// toBinaries converts a DynamoDB binary set at path as configured, returning nil if it is dropped
+func toBinaries(bs [][]byte, path string) interface{} {
+	binaryMode := binaryMode(path)
+	if binaryMode == BinaryDrop {
+		return nil
+	}
+	converted := make([]interface{}, len(bs))
+	for i, b := range bs {
+		converted[i] = encodeBinary(b, binaryMode)
+	}
+	return converted
+}
+
+func encodeBinary(b []byte, binaryMode string) interface{} {
+	switch binaryMode {
+	case BinaryBase64:
+		return base64.StdEncoding.EncodeToString(b)
+	case BinaryHex:
+		return hex.EncodeToString(b)
+	case BinarySHA256:
+		digest := sha256.Sum256(b)
+		return hex.EncodeToString(digest[:])
+	case BinarySize:
+		return map[string]interface{}{"size": len(b)}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToBinary(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	Conf.Documents.Binary = BinaryConfig{
+		Default: BinaryBase64,
+		Overrides: []Override{
+			{Path: ParsePathPattern("**.blob"), As: BinaryDrop},
+			{Path: ParsePathPattern("hex"), As: BinaryHex},
+			{Path: ParsePathPattern("digest"), As: BinarySHA256},
+			{Path: ParsePathPattern("meta.*"), As: BinarySize},
+		},
+	}
+	b := []byte("hello")
+
+	assert.Equal(t, "aGVsbG8=", toBinary(b, "data"))
+	assert.Nil(t, toBinary(b, "blob"))
+	assert.Equal(t, "68656c6c6f", toBinary(b, "hex"))
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", toBinary(b, "digest"))
+	assert.Equal(t, map[string]interface{}{"size": 5}, toBinary(b, "meta.thumbnail"))
+	assert.Equal(t, []interface{}{"68656c6c6f", "6869"}, toBinaries([][]byte{b, []byte("hi")}, "hex"))
+	assert.Nil(t, toBinaries([][]byte{b}, "blob"))
+
+	// dropped attributes are left out of the document entirely
+	item := toItem(events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{
+		"blob": events.NewBinaryAttribute(b),
+		"name": events.NewStringAttribute("hello"),
+	}), "attrs")
+	assert.Equal(t, map[string]interface{}{"name": "hello"}, item)
+}
+
+func TestToIdBinaryKeysIgnoreConfig(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	Conf.Documents.Binary.Default = BinaryDrop
+
+	id, err := toId(map[string]events.DynamoDBAttributeValue{
+		"key": events.NewBinaryAttribute([]byte("hello")),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `"aGVsbG8="`, id)
+}
@@ -1,13 +1,15 @@
-// Code generated by go-bindata.
+// Code generated by go-bindata. DO NOT EDIT.
 // sources:
-// kvconfig.yml
-// DO NOT EDIT!
+// kvconfig.yml (440B)
+// config.yml (9.186kB)
+// template.json (611B)
 
 package main
 
 import (
 	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -20,7 +22,7 @@ import (
 func bindataRead(data []byte, name string) ([]byte, error) {
 	gz, err := gzip.NewReader(bytes.NewBuffer(data))
 	if err != nil {
-		return nil, fmt.Errorf("Read %q: %v", name, err)
+		return nil, fmt.Errorf("read %q: %w", name, err)
 	}
 
 	var buf bytes.Buffer
@@ -28,7 +30,7 @@ func bindataRead(data []byte, name string) ([]byte, error) {
 	clErr := gz.Close()
 
 	if err != nil {
-		return nil, fmt.Errorf("Read %q: %v", name, err)
+		return nil, fmt.Errorf("read %q: %w", name, err)
 	}
 	if clErr != nil {
 		return nil, err
@@ -38,8 +40,9 @@ func bindataRead(data []byte, name string) ([]byte, error) {
 }
 
 type asset struct {
-	bytes []byte
-	info  os.FileInfo
+	bytes  []byte
+	info   os.FileInfo
+	digest [sha256.Size]byte
 }
 
 type bindataFileInfo struct {
@@ -68,7 +71,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _kvconfigYml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xac\x8f\x3d\xaa\xc3\x30\x10\x84\xfb\x77\x8a\x45\xbd\xde\x01\x74\x95\x60\x82\x22\x4d\x88\xc0\xf6\x9a\xfd\x29\x72\xfb\xa0\x20\xa7\x8a\x0b\x43\xca\x91\xf6\x9b\xdd\x4f\xd8\x0d\x9a\xfe\x88\x36\xe1\x02\xd5\x28\x28\x2c\x55\xa3\x7a\xe9\xb9\x7f\x11\x2d\xd9\xca\x03\x32\x12\x91\x35\x9b\x91\xe8\x42\xe1\x00\x0b\x34\xbd\x47\xd9\x6d\x73\xfb\x60\xcf\x0d\x89\x42\x9e\x21\xa6\x61\x3c\x2a\xa4\x41\x13\x85\x5a\x6f\xd1\x38\x42\xff\x8f\x4a\x07\x51\xdb\x82\x55\x1b\xaf\xda\x4f\x98\xf6\x1e\xcb\x76\x1d\x1b\x0a\xfb\x6a\x90\xf0\xc5\xeb\x9e\xdb\xec\x82\xb3\x5e\x03\xfb\xad\xd7\x5e\x7a\xde\xeb\x15\x00\x00\xff\xff\xbe\x4b\x19\xcb\xb8\x01\x00\x00")
+var _kvconfigYml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xac\x8f\x3d\xaa\xc3\x30\x10\x84\xfb\x77\x8a\x45\xbd\xde\x01\x74\x95\x60\x82\x22\x4d\x88\xc0\xf6\x9a\xfd\x29\x72\xfb\xa0\x20\xa7\x8a\x0b\x43\xca\x91\xf6\x9b\xdd\x4f\xd8\x0d\x9a\xfe\x88\x36\xe1\x02\xd5\x28\x28\x2c\x55\xa3\x7a\xe9\xb9\x7f\x11\x2d\xd9\xca\x03\x32\x12\x91\x35\x9b\x91\xe8\x42\xe1\x00\x0b\x34\xbd\x47\xd9\x6d\x73\xfb\x60\xcf\x0d\x89\x42\x9e\x21\xa6\x61\x3c\x2a\xa4\x41\x13\x85\x5a\x6f\xd1\x38\x42\xff\x8f\x4a\x07\x51\xdb\x82\x55\x1b\xaf\xda\x4f\x98\xf6\x1e\xcb\x76\x1d\x1b\x0a\xfb\x6a\x90\xf0\xc5\xeb\x9e\xdb\xec\x82\xb3\x5e\x03\xfb\xad\xd7\x5e\x7a\xde\xeb\x35\x00\xbe\x4b\x19\xcb\xb8\x01\x00\x00")
 
 func kvconfigYmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -83,8 +86,48 @@ func kvconfigYml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "kvconfig.yml", size: 440, mode: os.FileMode(436), modTime: time.Unix(1522800882, 0)}
-	a := &asset{bytes: bytes, info: info}
+	info := bindataFileInfo{name: "kvconfig.yml", size: 440, mode: os.FileMode(0664), modTime: time.Unix(1734639208, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x3, 0x43, 0x8f, 0xf6, 0x83, 0x1d, 0x2b, 0xeb, 0x56, 0xd4, 0xf5, 0x47, 0x75, 0x52, 0xbb, 0x1c, 0xf0, 0x7a, 0x58, 0xf7, 0xcc, 0xef, 0xbb, 0x92, 0x8a, 0x57, 0x75, 0xa4, 0xc8, 0x33, 0x68, 0x22}}
+	return a, nil
+}
+
+var _configYml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x9c\x5a\x5b\x73\xe3\xb6\x92\x7e\xf7\xaf\xe8\xb2\x1e\x66\x26\x25\xd3\x97\x99\x78\x12\xbd\x29\xb6\x92\x78\x8f\xc7\xf6\x5a\x9e\x4c\xa5\xb6\x4e\xa9\x20\xa2\x29\x22\x02\x01\x06\x00\x25\x2b\x67\xcf\x7f\xdf\xea\x06\x40\x51\x73\x3d\x9b\x87\xa4\xc6\x24\xd1\xf7\xfe\xfa\x02\x8d\xe0\x1a\x2b\xd1\xe9\x00\xa5\x35\x95\x5a\x75\x4e\x04\x65\xcd\x18\xb0\x59\xa2\x94\x28\x41\x19\x08\x35\xc2\x52\x19\xe1\x76\xc5\xd1\x08\x1e\xac\x32\x01\xae\xee\xef\x7e\xbe\xf9\x65\xf1\x30\x7d\xfa\x15\x44\x00\x61\x6c\xa8\xd1\x41\xa5\x34\x42\xb0\xe0\xb0\xd5\xa2\x44\x50\xa1\x80\x99\xd9\x28\x67\x4d\x83\x26\xc0\x46\x38\x25\x96\x1a\x3d\xd8\x0d\x3a\xa7\x24\x82\x32\x52\x6d\x94\xec\x84\x06\x8f\x21\x28\xb3\xf2\xc5\x11\x6a\xe1\x83\x2a\x3d\x0a\x57\xd6\x93\x23\x80\x51\x3e\x20\xd1\xc0\x72\x07\xb3\xdb\xe9\xfc\xe9\xe6\x6a\x3e\x9b\x3e\x5e\xfd\xba\x78\xff\x78\x7b\x04\xd0\x39\x3d\x81\x3a\x84\x76\x72\x7a\xaa\x6d\x29\x74\x6d\x7d\x98\xfc\x78\x71\x76\xc6\x04\x36\x97\x50\x59\x07\xb3\x21\x69\xb8\x1c\x83\x75\x10\x76\x2d\x6a\xf4\xfe\x33\x1f\xbc\x05\x61\x24\xfc\xc0\xff\xbf\x6f\xd1\xcc\xf9\x5c\x01\xf7\x5f\x11\x67\xfa\x70\x73\x04\x20\x5a\x35\x81\xcd\x25\xf3\x16\x01\x1a\xeb\x03\x58\x83\x60\x2b\x58\x0a\xaf\x4a\x10\x5d\xa8\xc7\x20\x0c\x4c\x1f\x6e\x60\x8d\x3b\x92\x64\xfa\x61\x0e\x5e\xad\x8c\x32\x2b\x22\xd1\x05\xd6\xfe\x9b\xfa\xcf\x67\x8f\x77\xd3\x77\x33\x96\xf2\xf0\xd5\xc3\x74\x3e\xff\x70\xff\x78\xcd\x54\x3a\x8f\xce\x88\x06\x27\x70\x7c\xcc\x0f\x5a\xe1\xfd\xd6\x3a\xd9\x3f\x18\x91\x6c\x78\xf9\x06\xd0\x94\x96\xfc\x7f\xac\xe4\x44\xb4\x6a\xb1\xc6\xdd\xf1\x37\xb5\x5e\xfc\x63\xf6\x3b\x93\x11\xad\xfa\x07\xee\x06\x54\x49\x27\x0f\x0e\xff\xec\xd0\x87\x68\xe7\x69\x23\xfe\xb2\x66\x60\x54\x98\xa3\xdb\xa8\x12\xc7\xb0\x55\xa1\x86\xd2\xa1\x44\x13\x94\xd0\x1e\x2a\x67\x1b\x8e\x43\x1f\x84\x91\xc2\x49\x36\x14\xee\x23\x2b\xb1\xe9\xe3\x6b\x0c\xbe\x16\x0e\x65\x8a\x6b\x32\xad\x32\x74\xb8\x44\x70\x56\x23\x7f\x2f\xb6\x3e\x5a\x17\xc8\xe6\xbf\xbd\x99\x40\x25\xb4\xc7\xf4\x68\x04\x32\xe6\x86\xa7\x88\x9e\x7e\x98\x2f\x1e\x67\xbf\xdc\xdc\xdf\xa5\xd7\x0e\x57\xca\x9a\x5e\x45\xfa\x1e\xa3\x62\x9f\x6a\x04\xd2\x36\x42\x19\xcf\xc1\x26\x6c\x0a\x34\x8f\x6e\x83\x8e\xe3\xae\xb4\x5a\x63\x49\xa9\xe7\x13\x35\x7a\xa9\x4a\x9c\x00\xd2\x93\xa0\x93\xa4\x23\x78\x98\xbd\x83\x65\x67\xa4\xe6\x48\x22\x9b\x94\xe8\x82\xaa\x54\x29\x02\x72\xc4\x58\xa7\x82\x42\x0f\xc1\x75\x3e\xa0\x1c\x53\x0e\xc7\x74\x4c\x07\xfc\xce\x07\x6c\x5e\x10\x61\x80\x52\xfc\xac\xf4\x3e\x22\x22\x83\x52\x2b\x4a\xd7\x03\xca\x46\x52\x90\xc6\x33\xe8\xc2\xc1\xa9\x35\xee\x06\x7f\x7f\x3d\x58\x6f\xee\xae\x6f\xae\x66\xf3\x31\x08\x28\x6d\xd3\x08\xf0\xd8\x0a\x27\x02\x4a\xd0\xca\x87\x82\x09\x5c\x8b\x80\x9e\x04\x5f\x3a\x51\xa2\x1f\x83\xef\xca\x1a\x84\x07\xdc\xa0\x09\xfe\xe4\x5f\xbb\xdd\x6e\x57\xbc\x7b\xf7\xef\x31\x08\x87\x64\xce\x46\x04\x22\xc1\x91\x22\x6d\xd9\x51\x54\xf8\x22\xa8\x06\x7d\x10\x4d\x4b\xb4\xde\x3f\x5d\x31\xf1\xce\x2b\xb3\x62\x4b\x10\x95\x31\xd0\x7f\xef\xde\x8d\x41\x4a\x4e\xa0\x5f\x7f\x85\x60\xd7\x68\x3c\x89\xa2\x8c\x54\x25\xfa\x09\xfc\xcf\x3f\xf9\xac\xb3\x1d\x49\xd6\x73\xa0\xd8\x88\xd8\x97\xbe\x24\x85\x89\xf4\x46\xe8\x8e\x2d\x2e\x72\x6e\x07\xdb\x9e\x68\xdc\xa0\x06\x11\x82\x53\xcb\x2e\x60\xaf\x17\xd3\x06\x70\x9d\x46\xf6\xf4\x88\xcc\x0c\x27\xfb\x2f\x27\xd0\xae\xfb\xe7\x00\xad\xc3\x4a\x3d\x4f\xe0\xf8\xfd\x7c\xf6\x38\x3a\x1e\xbc\xd9\x0b\x4c\xd9\xee\x49\x6a\x65\x24\x3e\x3f\xda\x2e\x28\xb3\xca\x61\x14\x9c\x8a\xe8\x6e\x9d\x44\x37\x66\x6b\x54\xca\xf9\x00\x8d\x08\x65\xad\xcc\x8a\x85\x81\x56\x95\x6b\xcf\x6f\x13\xe1\x02\x66\xa2\xac\xc1\x63\xf0\x43\x3d\xa2\x40\xa4\x26\xfe\xd9\x09\xed\x09\xd8\x64\x16\x86\x0c\x99\x95\x8b\x86\x24\x11\xfc\x5a\xb5\xe0\xb0\xb4\x4e\x7a\x30\x96\xdf\x47\xee\xe4\x70\x4a\x57\x14\x92\x2c\xb8\xa5\x80\x8e\x1e\x6b\xc8\xde\x89\x2a\x13\x95\xce\xb6\xef\x4d\x3c\x25\xf7\x09\x3c\x02\x87\xa4\xa2\xa7\xf3\xcb\x4e\xaf\x41\x05\x6c\x48\x11\x11\xa0\x12\x4a\x47\x8c\x11\xfc\xd9\x8e\x20\x03\xd0\x39\xeb\xf6\x81\xf6\xe6\xe2\x47\x4a\x10\x7e\x1f\x8d\xd6\x88\xe7\xc7\x48\x74\x02\xaf\xf9\x89\x32\x8a\xf0\xe9\x27\x51\xae\x6d\x55\x4d\xe0\xfb\xb3\xb3\xc6\xe7\x6f\xf7\x4f\xe3\xa3\x3f\x54\x08\xe8\x26\x70\x56\x5c\xb0\xbb\x7c\xab\x15\xd7\xbb\x41\x30\x29\x13\x6c\x14\x37\x43\xe5\x18\xbc\x8d\x52\x1b\x2a\x1e\xf8\x5c\x22\x4a\xd2\x03\xa1\xd4\x94\xdf\xee\x85\xe7\xa2\x57\x34\xe2\x79\x51\x5a\x13\xd0\x84\x85\x46\xb3\x0a\xf5\x11\x30\xad\xec\x72\x11\x01\x06\x5a\x74\x07\x3c\xc6\x5c\x96\xe8\x69\x16\x24\xfb\x0e\x9f\x0b\x78\x6f\xb4\x6a\x14\xa5\x96\xaa\xe0\xac\xc8\xda\x4d\x23\xb1\x09\x9c\x9f\x71\x7d\x25\x06\xe8\x83\x6a\x38\x91\x97\xbb\x80\x9f\x32\x2a\xe0\xfc\xec\xdd\x4f\xa0\xa2\xf8\xbe\x11\x5a\xa3\x0f\xc0\xf4\xc9\x4f\x5f\xac\x08\x5f\x94\xe2\x27\xe2\x43\x32\xbc\xf9\xe1\xfb\xb7\x97\x59\x8e\x21\x4f\x0f\x9e\x90\x4c\x50\xed\x2d\x29\x32\x80\x4a\x42\xd9\x39\x87\xa6\xdc\x4d\xe0\x9c\x7d\xc1\xf2\x20\x7b\xc3\xb3\xf2\x8d\x68\x5b\xfe\x23\x41\x66\x0a\x39\x8e\xa2\x81\xbf\x08\x7b\x28\x3a\x03\x1a\x08\x36\xf9\x0a\x77\x20\xad\x79\x11\xc0\xa1\xde\x81\x35\x20\x77\x46\x34\xaa\xcc\x44\x49\xfa\x11\xdc\x24\x8a\x1c\x87\x92\x10\xb6\x25\x04\x73\xc6\xc3\x0a\xa9\xa3\x8a\x0e\x80\x80\x4d\xab\x45\xc0\x02\x1e\xbb\xd4\x8b\x59\x1b\x7c\x70\xa2\x8d\xf8\x69\x24\xe5\x44\xe9\x90\x68\x70\x8a\x90\x02\x0e\x5b\xeb\x02\x48\xa7\x2a\x86\xd4\x4c\x26\x07\xc3\x7f\xcd\xef\xef\x62\xbb\xc6\x02\x7c\x56\xf9\x22\x77\x87\x5c\x01\x89\x75\x2f\xcc\x1f\xde\x9a\x54\x86\x64\x4c\xa5\x61\x97\x48\x2c\xaa\x61\x7d\x18\x65\xf9\x1a\xe5\x19\x7c\xb3\x3d\xfb\x40\xeb\x49\x7b\xb2\x98\x0f\xc2\x85\xae\x8d\x20\xa2\xed\x0a\xb6\x35\x3a\x8a\x7e\xe5\xc9\x47\xbd\x84\x51\xbf\x7d\x7b\x90\x89\x30\xd3\xde\x4e\x43\x58\x20\x6f\x91\x99\x9c\xed\x56\x35\x08\xad\x84\x47\x0f\xd4\x14\x49\x10\x07\x40\x37\x06\x24\xa0\x6b\xa9\xdf\x25\x9e\xc1\x82\x80\x0d\x3a\xaf\xac\xc1\x2c\x75\x46\x0b\xc6\xda\x93\xcd\x39\xe9\x3e\x82\xa7\xcf\x7a\x29\x9a\x80\x79\x34\x51\x33\xe2\xe6\x30\xea\x9f\xbf\x6a\xec\x26\x7d\x43\x46\x17\x60\x70\x9b\xb9\xb2\xa1\x6d\x17\x40\xda\xad\xa1\xca\x46\xdc\x58\xa1\x69\xd4\x23\x3a\x17\x0d\xe1\xd9\x00\x0c\x49\xa4\xda\x6e\xc1\x56\x14\xa7\x7c\xc0\x79\x28\x6b\x2c\xd7\x54\x39\xf7\xb1\xb6\x44\x52\x34\x49\xc4\x3a\x52\x50\x6f\x6b\x55\xb2\x7f\x77\xd9\x7a\x96\x54\xde\xa2\xd6\x4c\xdc\x61\xe5\xd0\xd7\x37\x26\xa0\xdb\x08\x3d\x81\xf3\x86\xad\xc0\x19\xcb\xee\x24\x3d\xbd\xfa\x8b\xcb\x21\x1b\x35\x67\xd0\x1e\xdd\xb4\x70\x2b\x84\x6b\xca\x14\x7b\xfd\x53\x42\xeb\x98\x46\x11\xf3\x3e\x82\xbc\x48\x3c\x35\x0b\x7d\x3e\x96\xb5\x30\x2b\xfa\xd6\x42\xa5\x02\xb7\x06\x8d\x70\xeb\x1c\xa3\x8b\xe0\x3a\x43\x7d\x92\x9c\x50\x77\x84\xd9\x0b\xd6\x53\x48\x88\x00\x3e\x28\xad\x53\xf6\x66\x02\x0e\xff\xc0\x32\xa0\x24\x5e\x59\xee\x5b\xe6\x9e\x73\x29\xa2\x9d\xad\x38\xa9\xbe\x85\x55\x19\xa4\x2a\x85\x5a\xfa\x31\x94\xb6\x4b\xf1\xc5\x62\xd8\x0a\x0c\x52\xdb\x06\x76\x49\x7c\x7d\x01\x57\xb6\x69\x49\x90\x58\xf9\xf0\xb9\x48\xd1\x5f\x04\x1b\x84\x5e\x44\x42\x05\xf3\xfc\x22\xf3\x9f\xf9\xa3\x3d\x77\x0a\x07\x89\xd8\x12\x40\x45\x3e\x6c\x0a\x6a\xc0\x3c\x34\x62\x07\x4b\x4c\x72\x8c\x53\xee\x09\xa8\xb4\x08\xbd\x05\x08\xc5\xcf\xbf\xc8\xee\x1a\xdb\x50\xef\xb9\x45\x13\xb2\x57\xa8\xf4\x92\x27\x33\x9d\x31\xe1\xb3\x24\xfd\x55\xc8\x28\x23\xa9\xee\x6b\x24\x34\x04\xaf\xcc\xba\x80\xec\x37\xd0\xd6\xac\xc0\x07\xc7\x58\x15\x2c\x34\xe2\x79\xce\x7f\x71\x25\x28\x12\x3f\x6a\x0a\x3e\xa3\x57\x32\x6c\xb0\x96\x75\xe7\xa6\x27\x42\x2a\x47\x9f\x0f\x9f\x6b\xd1\xfc\x98\x4f\xf7\xfd\x87\x32\xb0\x20\xfa\x6d\x8c\x08\x62\xe7\x43\xb7\xec\xbd\x03\xd6\xe8\xd8\x01\x7e\x8e\x58\x0c\x33\xe1\x90\x02\x3b\x4b\x68\x1d\xb3\xf0\x99\xde\x35\x6a\xa4\x88\x22\xa7\x1b\xdc\xa0\xeb\x63\x70\x0c\xcb\x2e\x80\xb7\x55\x00\xc9\x1f\xc9\xde\x90\x1e\x34\xc5\x4f\xa8\x51\x39\xa0\x11\x16\xd6\xc6\x6e\x0d\xa8\x46\xac\x30\x77\x53\x91\x43\x6b\xb5\xa2\xda\x17\xa9\x26\xa6\x24\x31\x59\x97\x2a\x72\x36\x30\x09\xd0\xa7\x0c\x69\xd8\x23\x7d\xef\x90\x48\xab\x80\xd7\x17\x6f\x2f\x2f\x73\x69\xe7\x59\xf7\xb6\x2b\xd1\x10\x67\xc2\x12\x4f\x88\xc1\x9d\x30\x8d\x9b\x7d\x9c\x0c\x7c\x37\x89\x24\x38\xa1\xf1\x99\x0a\xa1\xd0\xdc\x4e\xa6\x7f\x2f\x56\x21\xad\x16\x48\x68\xf0\x41\xe8\x58\x7b\xd1\x7f\x7d\x2e\xfd\x6d\xf6\x38\xbf\xb9\xbf\x5b\x3c\xfd\xfe\x30\x3b\x82\x8c\xa5\x4f\xbb\x76\x3f\xae\x30\x19\x68\xac\x54\x15\x77\xc5\x0c\x3e\xc2\x43\xd7\x52\x61\xe6\xd4\xee\xbd\xfa\xb1\x2f\x13\xe4\x8c\x61\x8d\x48\x35\x29\x25\x76\x1a\x0a\x5a\xd5\xa2\x56\x86\x9c\x29\x25\xeb\x16\xec\x41\xfc\xfb\x02\x1e\x91\x30\x5f\x0e\xa3\x84\x0c\xef\x91\xe2\x11\x4c\xa7\x35\x7d\xf3\x67\xa7\x1c\x7a\x8a\x7d\x14\x4d\x6a\x1b\xac\x96\xd1\xbf\xa9\xdd\x36\x76\xa8\x1e\xb3\xb3\xee\xa0\x42\x90\xe5\x5b\xe1\xa8\x6b\x7d\x1f\x95\x1b\x16\x47\xf6\x55\x6a\xc9\x53\xc0\x0f\xa5\x62\xe8\x8e\xfa\x92\x3a\x34\x5a\xba\x24\xbb\x75\x7b\x43\xe4\x20\x51\x8e\x05\x24\x5c\xe1\x22\x46\x03\x11\xc7\x01\xc3\x24\x4b\x37\xef\x5a\x6a\x53\x52\x43\x28\x1a\xfc\xa8\x13\x12\x3e\x57\xe3\x02\xae\x95\xe7\xba\x06\xaa\x02\x6c\xda\xb0\x23\x5d\x6a\xe5\x83\x75\xbb\x1b\x12\xbc\x77\x67\x5f\xc0\x85\xe7\xc4\x22\x97\x9a\x4c\x88\x62\x6a\x38\x10\x65\x7c\x93\x29\xe9\xa8\x5e\x0c\x92\x8a\x95\x59\xa4\x64\xcb\x55\x23\xff\x3d\x0d\xcc\x8f\x34\x5c\xe0\x73\xab\x68\xe1\xf0\x92\x3e\xe1\xb2\xfa\xf4\x74\x0b\xfc\x74\xf7\x6a\x38\xcd\xb0\xc9\x32\x9c\x14\xf0\x34\x08\x06\x8e\xa1\xd4\x84\xf0\x84\xfe\x49\x22\x8f\x99\x61\xac\xc8\xee\x1b\x31\x51\xc0\x95\x20\x98\x59\x22\x74\x3e\x97\xc0\x41\x7c\x90\xfd\x08\x4a\x22\xdc\xa4\x7e\x94\x07\xdd\x5e\x7d\xaa\x70\x4a\xe6\x3a\x47\x73\xec\x20\x1c\x1a\xb1\x26\x35\xba\xf6\x20\xa0\xe1\xe6\x7a\xdc\xcf\x95\x87\x1d\x24\x41\xd9\xee\x90\x0a\x78\xeb\x08\x5b\x96\x3b\xee\xc1\x8a\xbc\x4d\xd8\xcf\x89\x69\x3b\x60\xdd\x04\x8e\xff\x37\xa6\x2b\x37\x52\xca\x9a\x13\xea\x81\xc6\x1f\x5b\x63\xa0\xe1\x01\x78\xfc\x67\x28\x31\xbf\x7f\xff\x78\x35\xc0\x89\xb9\xed\x5c\xb9\x47\x0a\xd2\x74\xbf\x53\x60\x30\x77\xe8\xad\xa6\x9e\x4d\xe6\x9d\x05\x87\x17\xeb\x43\x93\x5f\xff\x79\x36\xe3\x81\xf8\xfd\x4e\x86\x7d\x98\x72\x2f\xee\x89\xcc\xc0\x4c\x79\x0c\xa1\xf1\x23\xb7\x4c\x31\xe1\x62\x0f\x9d\xa3\xb7\x52\xa9\xad\xf4\xa2\xc9\xad\x5f\x1a\xe4\xbe\x32\x70\x24\xdb\x1d\xb0\x8c\xae\xf0\x49\xfd\x03\x99\x13\xdd\xcf\xd4\x37\xa8\xad\xee\x91\xa3\x57\x9c\xe0\x2b\x4e\xf9\xb9\x81\x55\x71\x08\x8f\x3c\xfa\xd3\xc9\xca\x24\xb3\xab\xca\xd7\xaf\x5f\xff\xc8\x79\x14\xab\xd1\xfe\xb3\xb8\x46\xc3\xd6\x96\xf5\x89\xc7\xd2\x1a\xe9\xf7\x0f\x1a\xa5\xb5\x8a\xfb\x35\xd3\x35\x4b\x74\x83\x73\x4c\x3b\xae\x8a\x26\x99\x03\x7b\xb5\xec\x7c\xb0\x0d\xb8\x8c\x08\xd9\xc2\x0e\x69\x98\xc8\x05\x41\x93\xa1\x73\x37\x4b\x58\x45\x2b\x46\x59\x1c\x94\x6b\x22\x91\x7a\x55\xf2\x00\x6c\x05\xc3\x54\xa2\x3c\x4c\x24\xeb\xbe\x69\xc0\x74\x2a\xae\x92\x8a\x41\x9b\x3b\xe0\xb4\xdc\xc1\xcd\xf5\x01\x24\x7e\xde\xa0\xbc\x6d\xf0\x87\x88\xce\xfd\x3d\x8d\x27\xad\x35\x44\x77\xbf\xfc\x38\xbe\x7f\xfc\x65\x74\x7e\xf1\x7a\xc4\xeb\xa5\x37\xc7\xf1\x38\x65\xcd\xf1\xe8\xf8\x93\xb4\xcc\x3c\xfe\x42\x67\x4f\x68\x7f\x9c\xa7\xa3\x14\xb5\x3d\x87\x88\x43\xc2\x7f\xaa\x5d\x1a\xcc\xd3\x77\xb1\x73\x1c\x41\x6b\xdb\x8e\x3c\x40\xad\xc3\x1f\x56\x99\x58\x5b\x69\xae\x16\x7c\x94\xda\xe2\xb4\xad\x28\x6b\xa5\x07\xcd\xd0\x27\xa8\x90\xb8\x1d\x01\x13\xca\x8e\x30\x62\x9f\x81\x7b\x06\x5f\xa8\x35\x34\xd3\xa2\x1e\xee\xca\xff\x93\x25\x1a\xcb\x6a\x0d\xb5\x46\x1e\xc3\xff\x6b\x7b\x46\xd2\x8d\x13\xab\x56\x77\x31\xaa\x59\x51\x47\xfb\x86\x9a\x32\x38\x1a\xe0\x4b\xa0\x1c\xdf\xbf\xf0\x0c\xca\xa4\x60\x8c\x18\x25\x8b\xde\x7f\x69\x37\x97\xc4\xcc\xb8\x3b\x1a\xd4\x43\x89\x74\x26\x8f\x66\x2f\xfc\xa0\xb0\x11\xbc\x05\x9d\xad\x19\x8f\x80\x56\x6b\x5a\x17\xef\x52\x0b\x14\x9f\xc6\xfe\xe8\xb0\xf5\x81\xce\x04\xdb\x95\x35\x35\x0d\xc1\x36\x4b\x1f\x68\x11\x95\xbb\xec\x58\x2d\x62\x20\x09\x9f\x58\x7c\x52\xaf\x40\x5a\x86\x03\x5a\xb7\xab\x0d\x8d\x29\x3d\x25\x09\xa5\x6d\x89\x42\x7e\xc9\x5d\xc2\x00\x2e\x49\x9a\xe6\xa0\x23\x8e\xb2\x66\x5e\xdf\x6e\x4e\x0e\xee\xa9\x8a\xd4\x61\xf0\xf1\x21\xcb\x14\x31\x11\x8f\xfa\x45\x3e\x5d\x3a\xa5\x44\xa1\x0d\x44\x7c\x49\x44\x95\x09\xb8\x42\xc7\xa0\x56\x69\x2b\x28\x31\x2b\xa1\x35\x45\xfc\x52\x94\x6b\x2a\xa5\xb9\x43\xdf\xe3\x9c\x8f\xdf\x5e\xbe\x81\x92\xab\x3e\x21\x71\xe2\x84\xcf\xa2\x0c\x7a\x57\xa4\x53\x3d\x4f\x52\x9f\x18\x26\x62\xe3\xfc\x21\x3d\xeb\x27\x71\xea\xaf\xd2\xb7\x31\x54\xd2\xad\xc8\x84\x6f\xcd\x32\x8b\x5e\xfc\x41\xe8\xf7\x51\x49\xfd\x5c\x3d\x40\x97\x9b\xeb\x54\x85\x34\x0a\x06\x3b\x02\x0e\x5e\xf9\x92\x2d\x24\x48\x55\x55\x48\x81\xab\x77\xfb\xd5\x4e\x62\xcb\xfc\xd2\xf5\x42\xbf\x4d\x8e\x7b\xa7\x3e\x0c\x9d\x6d\xc7\xe9\x1e\x6b\x0c\x35\x3e\xf3\x65\xd0\xc5\xf7\x97\xf0\x92\x08\xd5\xf8\x0c\x52\xad\xd0\x87\x57\x64\x61\xde\x4b\xbc\xcc\xac\x85\x87\x7f\x1d\xd3\xa3\xe3\x49\x1c\xeb\xff\xfd\xea\x50\xe9\x48\x36\x71\x8a\x7c\x87\xe9\xf7\x15\xfd\xff\xbe\x7a\x23\x98\x7e\x44\x8a\x0a\x80\xb4\x7c\xe1\xb1\xe7\x42\x70\xe1\x73\x3b\xfb\x5d\xde\xa4\x73\x26\xd2\xce\x4d\xc7\x2f\x38\xfe\xbf\xfb\x8e\x1f\xa7\x0a\x19\x61\xba\xd9\xef\xae\x78\xd8\xf9\x58\xfc\x3c\xb9\x66\x3d\xc6\x20\x68\x5e\x8c\x9e\xe4\x64\x0d\xec\xf6\x34\x65\x2f\x51\xdb\x6d\xea\x71\x67\xfb\xb7\x6a\x6f\x87\x21\xb6\x2a\x53\xea\x4e\xe2\x17\x15\xee\x99\x1b\x1b\xfe\x86\x00\xd3\xb6\xd5\x74\x19\xc0\x43\x3a\x73\x92\x99\xb0\xb5\xc4\x1f\x9f\xf9\x69\x0e\xa1\x0f\x35\x2d\xca\x68\x06\x33\x12\xb6\xd6\xad\x2b\x6d\xb7\x7c\x9c\x2e\xae\xa2\xb0\xb9\x4a\xfb\x40\xa8\xd0\x08\x72\x3a\xad\x28\x5b\x6d\x25\xb1\xaa\x31\x65\x05\x15\x98\xb4\xa4\x61\xf2\x27\xf0\x21\x51\x2c\x32\xe9\x6b\xac\xf8\x2e\xc1\x9a\x82\xc9\xbd\x8b\xd4\x8e\x46\x70\xd5\xf1\x62\x96\x6f\xd4\xf6\x0b\xc1\x41\x76\x52\xdb\x98\xaa\xca\x56\x19\x69\xb7\x20\x7c\xbc\x6d\x25\xe1\xf2\xf5\x0a\xf7\x6f\x6c\x94\xb0\x45\x34\x31\xee\x5e\xb2\x22\x5e\x6d\xf0\xd5\xd1\x88\xe3\x82\x56\x51\x1a\x5e\xb2\x35\xf8\xf9\x7e\x25\xd3\x6a\xbb\xa3\xd2\xca\x78\x9a\x6e\x3f\x19\x79\xd3\x17\xb7\xa2\x59\x4a\xba\x4f\x89\x29\x2d\x72\x47\xbb\xdc\x81\x0a\x79\x46\x99\x3a\x43\xbf\x21\xc8\xed\xa0\xed\x82\xa7\xdf\x00\x70\xf4\x64\xf1\xed\x36\x55\x29\x31\xe4\x4a\x11\x4f\x17\x46\xb4\x76\x89\xf1\x94\x74\xa3\xc2\xda\x3a\x5b\xa2\xa7\xde\x42\x71\x28\x3b\x4c\xb1\x92\x68\xfa\xe2\xa8\xec\x02\x99\x90\x1c\x3c\x82\xeb\xd9\xc3\xed\xfd\xef\x8b\xd9\xdd\x6f\xe9\x26\x25\xbe\x04\xd1\x87\xc9\x3e\x64\x58\xb6\x98\x53\xc3\x80\x1d\xdc\x3d\x53\x9a\xb6\xce\xca\x8e\xaf\x44\xc6\x50\x52\x53\xec\x4e\x24\x6e\xfe\xd9\x67\x54\xe7\x4f\xb6\xe8\xc3\xc9\x39\x19\x34\xff\x71\x01\x8d\x5a\xc5\x5f\x5c\xc0\x4b\xba\xc3\xf1\x93\xd3\xd3\x95\x0a\x75\xb7\x2c\x4a\xdb\x9c\x5e\x31\xa1\x53\x29\x97\x27\xc1\x9e\xa0\x3f\x6d\x3b\xad\x4f\xdf\xfe\xf0\xaa\xcf\x54\x8f\x59\x43\x42\x7d\x5a\xda\xf5\x03\xb9\x29\xb9\x94\x0e\x38\x27\x0f\xd5\xc2\xc3\x92\x62\xc0\xd7\x69\x95\x4c\xe4\x12\x99\x49\x8a\xd0\x7c\xbb\xdd\x9f\x4e\xd7\xd2\x1c\x20\x13\xb8\x38\xbb\x78\x73\x72\xf6\xf6\xe4\xfc\xf2\xe9\xfc\xfb\xc9\xd9\xd9\xe4\xec\xec\xe4\xec\xed\xe4\xec\xec\x0b\xc7\x2f\xd2\x71\x0a\xbb\xaf\x9d\xe6\x3b\xb8\x74\x01\x9a\x6e\x30\x68\x4e\x40\xd9\x07\x72\xb0\x59\x8f\x7c\xd7\x42\x9e\x76\x7c\x2f\x27\x3f\x1e\xf1\x7e\x9e\xde\xdc\x2e\xee\xef\x16\xb3\xc7\xc7\xfb\xc7\x23\xe0\x99\xe3\xde\xcc\x88\xc9\x7e\xed\x41\xdb\xc6\x5b\x5e\x36\xe6\xec\xe7\xab\x8b\xd3\xd3\x31\xf8\xd7\x93\xd3\x53\x8a\x71\xff\x27\xb9\x06\x24\xdd\x70\x99\xe8\xb0\xca\x3a\xba\x49\x6b\x04\xb5\xce\x7a\x97\xf6\x52\x38\xe8\x41\x3f\x96\xe6\x7a\x36\xbd\x5e\xdc\xce\x9e\x9e\x66\x8f\xe9\x17\x2b\xe9\x37\x2b\x7d\x3f\x39\x7f\x4d\xcc\xe6\xff\x3d\xe7\x56\x58\x04\xc5\xf7\x92\x46\xf2\x1d\xc4\xd7\xc8\xcd\xee\xae\x1f\xee\x6f\xee\x9e\xd2\xfe\x5f\xb6\x56\x99\x30\x81\xe3\xe3\xa3\xff\x1b\x00\x93\x85\x8d\x25\xe2\x23\x00\x00")
+
+func configYmlBytes() ([]byte, error) {
+	return bindataRead(
+		_configYml,
+		"config.yml",
+	)
+}
+
+func configYml() (*asset, error) {
+	bytes, err := configYmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "config.yml", size: 9186, mode: os.FileMode(0644), modTime: time.Unix(1792316341, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x61, 0xbf, 0x85, 0x13, 0xeb, 0x7b, 0x6f, 0x3, 0x11, 0x66, 0x4a, 0x3f, 0xb5, 0x72, 0xb6, 0x4, 0x8b, 0x9e, 0x3a, 0xff, 0x68, 0xdd, 0x7f, 0x77, 0xe, 0xad, 0xa4, 0x4e, 0xc3, 0x11, 0x75, 0xbb}}
+	return a, nil
+}
+
+var _templateJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x90\xc1\x6a\x04\x21\x0c\x86\xef\x3e\x45\xc8\x79\x4e\x85\xf6\xb0\xb7\x3e\x47\x29\xe2\x8e\xe9\x56\x3a\xa3\x21\x93\xb6\x3b\x2c\xbe\x7b\x71\x76\xa4\xab\xb4\xb0\x44\x50\x93\x2f\x7f\x7e\xbd\x18\x00\x5c\x48\x35\xc4\xd3\x82\x07\xb8\xe4\xa1\x64\x66\xc7\x5c\x33\x06\x00\x00\xfd\x1a\xdd\x1c\x46\xab\x34\xf3\xe4\x94\x0a\xfc\xb2\x95\x60\x47\xca\xc2\x45\xa5\xe9\xdb\xd3\xb3\xd3\xf1\xdd\xee\xaa\x56\x57\x26\x3c\x54\x18\x87\x96\xdc\x98\x4e\x00\x00\x6b\x93\xd2\x59\x9b\x16\x00\x7c\x0b\x34\xf9\x7e\x68\x09\xfc\xa0\xf5\x3b\x89\xff\xa3\x74\x23\x59\xa1\x56\xb5\x04\x86\x53\x4c\x42\xd6\x1d\xd3\x57\x41\x1f\x1e\x9f\x3a\x26\x9b\xff\x6e\xd9\xf4\xa7\xeb\xfe\x7a\x1d\x83\x2c\x89\x49\x34\xd0\xad\x71\xb4\x9e\x26\x52\xda\x1c\xff\x3a\x3c\xa6\x34\x91\x8b\x08\x79\xe8\xc1\x67\x6d\x51\xef\x94\x1a\x8e\xce\x1c\xe4\x1e\x41\x95\xcf\x38\xba\xfb\x66\x4b\x62\xee\xc1\xfa\x8d\xfb\x3b\xb3\x01\xc8\x26\x9b\x9f\x01\x00\x5e\xd9\xa2\x1e\x63\x02\x00\x00")
+
+func templateJsonBytes() ([]byte, error) {
+	return bindataRead(
+		_templateJson,
+		"template.json",
+	)
+}
+
+func templateJson() (*asset, error) {
+	bytes, err := templateJsonBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "template.json", size: 611, mode: os.FileMode(0644), modTime: time.Unix(1792313775, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x8a, 0xc4, 0xdb, 0xa1, 0xb4, 0xd6, 0x51, 0x60, 0x6, 0x46, 0xe9, 0xb6, 0x35, 0x78, 0xdc, 0x3f, 0x65, 0x7d, 0xde, 0x3b, 0xc8, 0x4b, 0x17, 0x67, 0x94, 0xa4, 0xc1, 0xe4, 0xd2, 0xa7, 0x50, 0xf4}}
 	return a, nil
 }
 
@@ -92,8 +135,8 @@ func kvconfigYml() (*asset, error) {
 // It returns an error if the asset could not be found or
 // could not be loaded.
 func Asset(name string) ([]byte, error) {
-	cannonicalName := strings.Replace(name, "\\", "/", -1)
-	if f, ok := _bindata[cannonicalName]; ok {
+	canonicalName := strings.Replace(name, "\\", "/", -1)
+	if f, ok := _bindata[canonicalName]; ok {
 		a, err := f()
 		if err != nil {
 			return nil, fmt.Errorf("Asset %s can't read by error: %v", name, err)
@@ -103,6 +146,12 @@ func Asset(name string) ([]byte, error) {
 	return nil, fmt.Errorf("Asset %s not found", name)
 }
 
+// AssetString returns the asset contents as a string (instead of a []byte).
+func AssetString(name string) (string, error) {
+	data, err := Asset(name)
+	return string(data), err
+}
+
 // MustAsset is like Asset but panics when Asset would return an error.
 // It simplifies safe initialization of global variables.
 func MustAsset(name string) []byte {
@@ -114,12 +163,18 @@ func MustAsset(name string) []byte {
 	return a
 }
 
+// MustAssetString is like AssetString but panics when Asset would return an
+// error. It simplifies safe initialization of global variables.
+func MustAssetString(name string) string {
+	return string(MustAsset(name))
+}
+
 // AssetInfo loads and returns the asset info for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
 func AssetInfo(name string) (os.FileInfo, error) {
-	cannonicalName := strings.Replace(name, "\\", "/", -1)
-	if f, ok := _bindata[cannonicalName]; ok {
+	canonicalName := strings.Replace(name, "\\", "/", -1)
+	if f, ok := _bindata[canonicalName]; ok {
 		a, err := f()
 		if err != nil {
 			return nil, fmt.Errorf("AssetInfo %s can't read by error: %v", name, err)
@@ -129,6 +184,33 @@ func AssetInfo(name string) (os.FileInfo, error) {
 	return nil, fmt.Errorf("AssetInfo %s not found", name)
 }
 
+// AssetDigest returns the digest of the file with the given name. It returns an
+// error if the asset could not be found or the digest could not be loaded.
+func AssetDigest(name string) ([sha256.Size]byte, error) {
+	canonicalName := strings.Replace(name, "\\", "/", -1)
+	if f, ok := _bindata[canonicalName]; ok {
+		a, err := f()
+		if err != nil {
+			return [sha256.Size]byte{}, fmt.Errorf("AssetDigest %s can't read by error: %v", name, err)
+		}
+		return a.digest, nil
+	}
+	return [sha256.Size]byte{}, fmt.Errorf("AssetDigest %s not found", name)
+}
+
+// Digests returns a map of all known files and their checksums.
+func Digests() (map[string][sha256.Size]byte, error) {
+	mp := make(map[string][sha256.Size]byte, len(_bindata))
+	for name := range _bindata {
+		a, err := _bindata[name]()
+		if err != nil {
+			return nil, err
+		}
+		mp[name] = a.digest
+	}
+	return mp, nil
+}
+
 // AssetNames returns the names of the assets.
 func AssetNames() []string {
 	names := make([]string, 0, len(_bindata))
@@ -140,27 +222,34 @@ func AssetNames() []string {
 
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
-	"kvconfig.yml": kvconfigYml,
+	"kvconfig.yml":  kvconfigYml,
+	"config.yml":    configYml,
+	"template.json": templateJson,
 }
 
+// AssetDebug is true if the assets were built with the debug flag enabled.
+const AssetDebug = false
+
 // AssetDir returns the file names below a certain
 // directory embedded in the file by go-bindata.
 // For example if you run go-bindata on data/... and data contains the
 // following hierarchy:
-//     data/
-//       foo.txt
-//       img/
-//         a.png
-//         b.png
-// then AssetDir("data") would return []string{"foo.txt", "img"}
-// AssetDir("data/img") would return []string{"a.png", "b.png"}
-// AssetDir("foo.txt") and AssetDir("notexist") would return an error
+//
+//	data/
+//	  foo.txt
+//	  img/
+//	    a.png
+//	    b.png
+//
+// then AssetDir("data") would return []string{"foo.txt", "img"},
+// AssetDir("data/img") would return []string{"a.png", "b.png"},
+// AssetDir("foo.txt") and AssetDir("notexist") would return an error, and
 // AssetDir("") will return []string{"data"}.
 func AssetDir(name string) ([]string, error) {
 	node := _bintree
 	if len(name) != 0 {
-		cannonicalName := strings.Replace(name, "\\", "/", -1)
-		pathList := strings.Split(cannonicalName, "/")
+		canonicalName := strings.Replace(name, "\\", "/", -1)
+		pathList := strings.Split(canonicalName, "/")
 		for _, p := range pathList {
 			node = node.Children[p]
 			if node == nil {
@@ -184,10 +273,12 @@ type bintree struct {
 }
 
 var _bintree = &bintree{nil, map[string]*bintree{
-	"kvconfig.yml": &bintree{kvconfigYml, map[string]*bintree{}},
+	"config.yml":    {configYml, map[string]*bintree{}},
+	"kvconfig.yml":  {kvconfigYml, map[string]*bintree{}},
+	"template.json": {templateJson, map[string]*bintree{}},
 }}
 
-// RestoreAsset restores an asset under the given directory
+// RestoreAsset restores an asset under the given directory.
 func RestoreAsset(dir, name string) error {
 	data, err := Asset(name)
 	if err != nil {
@@ -205,14 +296,10 @@ func RestoreAsset(dir, name string) error {
 	if err != nil {
 		return err
 	}
-	err = os.Chtimes(_filePath(dir, name), info.ModTime(), info.ModTime())
-	if err != nil {
-		return err
-	}
-	return nil
+	return os.Chtimes(_filePath(dir, name), info.ModTime(), info.ModTime())
 }
 
-// RestoreAssets restores an asset under the given directory recursively
+// RestoreAssets restores an asset under the given directory recursively.
 func RestoreAssets(dir, name string) error {
 	children, err := AssetDir(name)
 	// File
@@ -230,6 +317,6 @@ func RestoreAssets(dir, name string) error {
 }
 
 func _filePath(dir, name string) string {
-	cannonicalName := strings.Replace(name, "\\", "/", -1)
-	return filepath.Join(append([]string{dir}, strings.Split(cannonicalName, "/")...)...)
+	canonicalName := strings.Replace(name, "\\", "/", -1)
+	return filepath.Join(append([]string{dir}, strings.Split(canonicalName, "/")...)...)
 }
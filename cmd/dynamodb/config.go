@@ -0,0 +1,531 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/Clever/ddb-to-es/deadletter"
+	"github.com/Clever/ddb-to-es/es"
+)
+
+// Config is the declarative configuration of the Lambda
+type Config struct {
+	Elasticsearch ElasticsearchConfig `yaml:"elasticsearch"`
+	Documents     DocumentsConfig     `yaml:"documents"`
+	Cutover       CutoverConfig       `yaml:"cutover"`
+	Errors        ErrorsConfig        `yaml:"errors"`
+}
+
+// ElasticsearchConfig specifies how documents are written to Elasticsearch
+type ElasticsearchConfig struct {
+	URL          string             `yaml:"url"`
+	API          string             `yaml:"api"`
+	Auth         AuthConfig         `yaml:"auth"`
+	TLS          TLSConfig          `yaml:"tls"`
+	Indices      []string           `yaml:"indices"`
+	IndexRouting IndexRoutingConfig `yaml:"indexRouting"`
+	Retry        RetryConfig        `yaml:"retry"`
+	Bulk         BulkConfig         `yaml:"bulk"`
+	// DocumentLimits limits the size of each document
+	DocumentLimits LimitsConfig `yaml:"documentLimits"`
+	// Template declares the mappings of the indices written to
+	Template TemplateConfig `yaml:"template"`
+	// WriteAliases writes through aliases named as the indices, so they can be reindexed without downtime
+	WriteAliases WriteAliasesConfig `yaml:"writeAliases"`
+	VersionType  string             `yaml:"versionType"`
+	// PartialUpdates writes modified items as partial updates of the changed attributes
+	PartialUpdates bool `yaml:"partialUpdates"`
+	// HistoryIndex records the changes each record makes to its item. Disabled if empty.
+	HistoryIndex string `yaml:"historyIndex"`
+	// SoftDeleteIndices lists the indices where deletes mark documents as deleted instead of removing them
+	SoftDeleteIndices []string `yaml:"softDeleteIndices"`
+}
+
+// BulkConfig specifies how documents are split into bulk requests
+type BulkConfig struct {
+	MaxActions  int `yaml:"maxActions"`
+	MaxBytes    int `yaml:"maxBytes"`
+	Concurrency int `yaml:"concurrency"`
+}
+
+// WriteAliasesConfig specifies how documents are written through write aliases
+type WriteAliasesConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// RefreshInterval is how often writers check for indices being reindexed into
+	RefreshInterval time.Duration `yaml:"refreshInterval"`
+}
+
+// LimitsConfig limits the size of each document
+type LimitsConfig struct {
+	MaxBytes       int    `yaml:"maxBytes"`
+	MaxFields      int    `yaml:"maxFields"`
+	MaxDepth       int    `yaml:"maxDepth"`
+	MaxStringBytes int    `yaml:"maxStringBytes"`
+	Policy         string `yaml:"policy"`
+}
+
+// AuthConfig specifies how requests to Elasticsearch are authenticated
+type AuthConfig struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// APIKey is the base64 encoded "id:api_key" of an Elasticsearch API key
+	APIKey string        `yaml:"apiKey"`
+	AWS    AWSAuthConfig `yaml:"aws"`
+}
+
+// AWSAuthConfig specifies how requests to Amazon OpenSearch Service are signed
+type AWSAuthConfig struct {
+	SigV4   bool   `yaml:"sigV4"`
+	Region  string `yaml:"region"`
+	Service string `yaml:"service"`
+}
+
+// TLSConfig specifies how the TLS connection to Elasticsearch is verified and authenticated
+type TLSConfig struct {
+	CAFile   string `yaml:"caFile"`
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+}
+
+// RetryConfig specifies how bulk items that fail with a retryable error are re-submitted
+type RetryConfig struct {
+	MaxRetries     int           `yaml:"maxRetries"`
+	InitialBackoff time.Duration `yaml:"initialBackoff"`
+	MaxBackoff     time.Duration `yaml:"maxBackoff"`
+	Jitter         float64       `yaml:"jitter"`
+}
+
+// DocumentsConfig specifies how DynamoDB records are converted to documents
+type DocumentsConfig struct {
+	ID            IDConfig        `yaml:"id"`
+	VersionSource string          `yaml:"versionSource"`
+	Timestamp     TimestampConfig `yaml:"timestamp"`
+	Routing       RoutingConfig   `yaml:"routing"`
+	Join          JoinConfig      `yaml:"join"`
+	TTL           TTLConfig       `yaml:"ttl"`
+	Numbers       NumbersConfig   `yaml:"numbers"`
+	Binary        BinaryConfig    `yaml:"binary"`
+	// Include lists patterns of the only attribute paths that are indexed, along with everything
+	// nested below them. Every attribute is indexed if empty.
+	Include []PathPattern `yaml:"include"`
+	// Exclude lists patterns of attribute paths that are not indexed, along with everything nested below them.
+	// Exclusions apply to included attributes too.
+	Exclude []PathPattern `yaml:"exclude"`
+}
+
+// NumbersConfig specifies how DynamoDB numbers are indexed
+type NumbersConfig struct {
+	// Default is typed or string
+	Default   string     `yaml:"default"`
+	Overrides []Override `yaml:"overrides"`
+}
+
+// BinaryConfig specifies how DynamoDB binary attributes are indexed
+type BinaryConfig struct {
+	// Default is drop, base64, hex, sha256 or size
+	Default   string     `yaml:"default"`
+	Overrides []Override `yaml:"overrides"`
+}
+
+// Override converts attributes at matching paths differently from the default
+type Override struct {
+	Path PathPattern `yaml:"path"`
+	As   string      `yaml:"as"`
+}
+
+// mode returns how the attribute at path is converted: the As of the first matching override, or def
+func mode(overrides []Override, def, path string) string {
+	for _, override := range overrides {
+		if override.Path.Match(path) {
+			return override.As
+		}
+	}
+	return def
+}
+
+// writesTo returns true if index is one of the configured indices, including those of routing rules
+func (c ElasticsearchConfig) writesTo(index string) bool {
+	if contains(c.Indices, index) {
+		return true
+	}
+	for _, rule := range c.IndexRouting.Rules {
+		if contains(rule.Indices, index) {
+			return true
+		}
+	}
+	return false
+}
+
+// IDConfig specifies how document IDs are built from DynamoDB keys
+type IDConfig struct {
+	// Keys lists the key attributes making up the ID, in order. Every key attribute sorted by name if empty.
+	Keys      []string `yaml:"keys"`
+	Separator string   `yaml:"separator"`
+}
+
+// ErrorsConfig specifies how failures are handled
+type ErrorsConfig struct {
+	FailOnError bool             `yaml:"failOnError"`
+	DeadLetter  DeadLetterConfig `yaml:"deadLetter"`
+}
+
+// DeadLetterConfig specifies where permanently rejected documents are sent
+type DeadLetterConfig struct {
+	URL      string `yaml:"url"`
+	Endpoint string `yaml:"endpoint"`
+}
+
+// DBConfig converts the config to the es package's
+func (c ElasticsearchConfig) DBConfig() *es.DBConfig {
+	config := &es.DBConfig{
+		URL: c.URL,
+		API: c.API,
+		Auth: es.AuthConfig{
+			Username:   c.Auth.Username,
+			Password:   c.Auth.Password,
+			APIKey:     c.Auth.APIKey,
+			AWSSigV4:   c.Auth.AWS.SigV4,
+			AWSRegion:  c.Auth.AWS.Region,
+			AWSService: c.Auth.AWS.Service,
+		},
+		TLS: es.TLSConfig{
+			CAFile:   c.TLS.CAFile,
+			CertFile: c.TLS.CertFile,
+			KeyFile:  c.TLS.KeyFile,
+		},
+		Retry: es.RetryConfig{
+			MaxRetries:     c.Retry.MaxRetries,
+			InitialBackoff: c.Retry.InitialBackoff,
+			MaxBackoff:     c.Retry.MaxBackoff,
+			Jitter:         c.Retry.Jitter,
+		},
+		Bulk: es.BulkConfig{
+			MaxActions:  c.Bulk.MaxActions,
+			MaxBytes:    c.Bulk.MaxBytes,
+			Concurrency: c.Bulk.Concurrency,
+		},
+		Limits: es.LimitsConfig{
+			MaxBytes:       c.DocumentLimits.MaxBytes,
+			MaxFields:      c.DocumentLimits.MaxFields,
+			MaxDepth:       c.DocumentLimits.MaxDepth,
+			MaxStringBytes: c.DocumentLimits.MaxStringBytes,
+			Policy:         c.DocumentLimits.Policy,
+		},
+		VersionType:       c.VersionType,
+		SoftDeleteIndices: c.SoftDeleteIndices,
+	}
+	if c.WriteAliases.Enabled {
+		config.Aliases = &es.AliasConfig{RefreshInterval: c.WriteAliases.RefreshInterval}
+	}
+	return config
+}
+
+// defaultConfig returns the embedded config.yml, without environment variable overrides
+func defaultConfig() Config {
+	config := Config{}
+	if err := yaml.UnmarshalStrict(MustAsset("config.yml"), &config); err != nil {
+		panic(fmt.Sprintf("invalid embedded config: %s", err))
+	}
+	return config
+}
+
+// validate prints every problem with the config, returning the process exit code
+func validate(config Config) int {
+	problems := config.Validate()
+	for _, problem := range problems {
+		fmt.Fprintln(os.Stderr, problem)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "%d problems found\n", len(problems))
+		return 1
+	}
+	fmt.Println("config is valid")
+	return 0
+}
+
+// loadConfig reads the config from the file at CONFIG_PATH, or the embedded config.yml if unset,
+// and applies environment variable overrides
+func loadConfig() (Config, error) {
+	config := defaultConfig()
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("could not read config: %s", err)
+		}
+		if config, err = parseConfig(data); err != nil {
+			return Config{}, err
+		}
+	}
+
+	config.applyEnv(os.LookupEnv)
+	return config, nil
+}
+
+// parseConfig parses a YAML config, rejecting unknown fields.
+// Settings missing from it keep their value from the embedded config.yml.
+func parseConfig(data []byte) (Config, error) {
+	config := defaultConfig()
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return Config{}, fmt.Errorf("could not parse config: %s", err)
+	}
+	return config, nil
+}
+
+// applyEnv overrides settings with the environment variables that are set
+func (c *Config) applyEnv(lookup func(string) (string, bool)) {
+	if v, ok := lookup("ELASTICSEARCH_URL"); ok && v != "" {
+		c.Elasticsearch.URL = v
+	}
+	if v, ok := lookup("ELASTICSEARCH_API"); ok && v != "" {
+		c.Elasticsearch.API = v
+	}
+	if v, ok := lookup("ELASTICSEARCH_USERNAME"); ok && v != "" {
+		c.Elasticsearch.Auth.Username = v
+	}
+	if v, ok := lookup("ELASTICSEARCH_PASSWORD"); ok && v != "" {
+		c.Elasticsearch.Auth.Password = v
+	}
+	if v, ok := lookup("ELASTICSEARCH_API_KEY"); ok && v != "" {
+		c.Elasticsearch.Auth.APIKey = v
+	}
+	if v, ok := lookup("ELASTICSEARCH_INDICES"); ok && v != "" {
+		// Parse a comma separated list of Elasticsearch indices.
+		c.Elasticsearch.Indices = []string{}
+		for _, index := range strings.Split(v, ",") {
+			if index != "" {
+				c.Elasticsearch.Indices = append(c.Elasticsearch.Indices, strings.TrimSpace(index))
+			}
+		}
+	}
+	if v, ok := lookup("ELASTICSEARCH_VERSION_TYPE"); ok && v != "" {
+		c.Elasticsearch.VersionType = v
+	}
+	if v, ok := lookup("ELASTICSEARCH_VERSION_SOURCE"); ok && v != "" {
+		c.Documents.VersionSource = v
+	}
+	if v, ok := lookup("FAIL_ON_ERROR"); ok && v != "" {
+		// invalid values are treated as false, as they always have been
+		c.Errors.FailOnError, _ = strconv.ParseBool(v)
+	}
+	if v, ok := lookup("DEAD_LETTER_URL"); ok && v != "" {
+		c.Errors.DeadLetter.URL = v
+	}
+	if v, ok := lookup("DEAD_LETTER_ENDPOINT"); ok && v != "" {
+		c.Errors.DeadLetter.Endpoint = v
+	}
+}
+
+// Validate returns every problem with the config
+func (c Config) Validate() []error {
+	problems := []error{}
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	if u, err := url.Parse(c.Elasticsearch.URL); c.Elasticsearch.URL == "" {
+		problem("elasticsearch.url is required")
+	} else if err != nil || u.Scheme == "" || u.Host == "" {
+		problem("elasticsearch.url %q is not a valid URL", c.Elasticsearch.URL)
+	}
+	switch c.Elasticsearch.API {
+	case es.APIv6, es.APITypeless:
+	default:
+		problem("elasticsearch.api %q must be %s or %s", c.Elasticsearch.API, es.APIv6, es.APITypeless)
+	}
+
+	auth := c.Elasticsearch.Auth
+	methods := 0
+	for _, set := range []bool{auth.Username != "", auth.APIKey != "", auth.AWS.SigV4} {
+		if set {
+			methods++
+		}
+	}
+	if methods > 1 {
+		problem("elasticsearch.auth must use only one of username, apiKey or aws.sigV4")
+	}
+	if auth.Password != "" && auth.Username == "" {
+		problem("elasticsearch.auth.username is required with a password")
+	}
+	if (c.Elasticsearch.TLS.CertFile == "") != (c.Elasticsearch.TLS.KeyFile == "") {
+		problem("elasticsearch.tls.certFile and keyFile must be set together")
+	}
+
+	if len(c.Elasticsearch.Indices) == 0 {
+		problem("elasticsearch.indices must list at least one index")
+	}
+	for i, index := range c.Elasticsearch.Indices {
+		if strings.TrimSpace(index) == "" {
+			problem("elasticsearch.indices[%d] is empty", i)
+		} else if err := es.ValidateIndexName(index); err != nil {
+			problem("elasticsearch.indices[%d]: %s", i, err)
+		}
+	}
+
+	problems = append(problems, c.Elasticsearch.IndexRouting.validate()...)
+	if err := es.ValidateIndexName(c.Elasticsearch.HistoryIndex); err != nil {
+		problem("elasticsearch.historyIndex: %s", err)
+	}
+	for i, index := range c.Elasticsearch.SoftDeleteIndices {
+		if !c.Elasticsearch.writesTo(index) {
+			problem("elasticsearch.softDeleteIndices[%d] %q is not one of the indices written to", i, index)
+		}
+	}
+
+	retry := c.Elasticsearch.Retry
+	if retry.MaxRetries < 0 {
+		problem("elasticsearch.retry.maxRetries must not be negative")
+	}
+	if retry.InitialBackoff < 0 || retry.MaxBackoff < 0 {
+		problem("elasticsearch.retry backoffs must not be negative")
+	}
+	if retry.MaxBackoff > 0 && retry.MaxBackoff < retry.InitialBackoff {
+		problem("elasticsearch.retry.maxBackoff must not be less than initialBackoff")
+	}
+	if retry.Jitter < 0 || retry.Jitter > 1 {
+		problem("elasticsearch.retry.jitter must be between 0 and 1")
+	}
+
+	bulk := c.Elasticsearch.Bulk
+	if bulk.MaxActions < 0 || bulk.MaxBytes < 0 {
+		problem("elasticsearch.bulk limits must not be negative")
+	}
+	if bulk.Concurrency < 1 {
+		problem("elasticsearch.bulk.concurrency must be at least 1")
+	}
+
+	if _, err := loadTemplate(c.Elasticsearch.Template.File); err != nil {
+		problem("elasticsearch.template.file: %s", err)
+	}
+	if aliases := c.Elasticsearch.WriteAliases; aliases.Enabled {
+		if aliases.RefreshInterval <= 0 {
+			problem("elasticsearch.writeAliases.refreshInterval must be positive")
+		}
+		for _, index := range c.itemIndices() {
+			if strings.Contains(index, "{") {
+				problem("elasticsearch.writeAliases can't be used with index %s, which has date patterns", index)
+			}
+		}
+		if c.Elasticsearch.PartialUpdates {
+			// partial updates of an index being reindexed into would stop the reindex copying the full documents
+			problem("elasticsearch.writeAliases can't be used with elasticsearch.partialUpdates")
+		}
+	}
+
+	limits := c.Elasticsearch.DocumentLimits
+	if limits.MaxBytes < 0 || limits.MaxFields < 0 || limits.MaxDepth < 0 {
+		problem("elasticsearch.documentLimits must not be negative")
+	}
+	switch limits.Policy {
+	case es.LimitPolicyReject, es.LimitPolicyDrop, es.LimitPolicyStub:
+	case es.LimitPolicyTruncate:
+		if limits.MaxStringBytes <= 0 {
+			problem("elasticsearch.documentLimits.maxStringBytes must be positive with the %s policy", limits.Policy)
+		}
+	default:
+		problem("elasticsearch.documentLimits.policy %q must be one of %s, %s, %s, %s", limits.Policy,
+			es.LimitPolicyReject, es.LimitPolicyTruncate, es.LimitPolicyDrop, es.LimitPolicyStub)
+	}
+
+	switch c.Elasticsearch.VersionType {
+	case "", es.VersionTypeExternal, es.VersionTypeExternalGTE:
+	default:
+		problem("elasticsearch.versionType %q must be %s or %s",
+			c.Elasticsearch.VersionType, es.VersionTypeExternal, es.VersionTypeExternalGTE)
+	}
+	switch c.Documents.VersionSource {
+	case "", VersionSourceCreationTime:
+	default:
+		problem("documents.versionSource %q must be %s", c.Documents.VersionSource, VersionSourceCreationTime)
+	}
+	if (c.Documents.VersionSource == "") != (c.Elasticsearch.VersionType == "") {
+		problem("documents.versionSource and elasticsearch.versionType must be set together")
+	}
+	if c.Documents.VersionSource == VersionSourceCreationTime && c.Elasticsearch.VersionType == es.VersionTypeExternal {
+		// with second precision, the later of two changes in the same second would be rejected as stale
+		problem("documents.versionSource %s requires elasticsearch.versionType %s",
+			VersionSourceCreationTime, es.VersionTypeExternalGTE)
+	}
+	if c.Elasticsearch.PartialUpdates && c.Elasticsearch.VersionType != "" {
+		// the update API doesn't support external versioning
+		problem("elasticsearch.partialUpdates can't be used with elasticsearch.versionType")
+	}
+	if len(c.Elasticsearch.SoftDeleteIndices) > 0 && c.Elasticsearch.VersionType != "" {
+		problem("elasticsearch.softDeleteIndices can't be used with elasticsearch.versionType")
+	}
+	if policy := c.Documents.TTL.Policy; (policy == TTLPolicyTombstone || policy == TTLPolicyArchive) && c.Elasticsearch.VersionType != "" {
+		problem("documents.ttl.policy %s can't be used with elasticsearch.versionType", policy)
+	}
+
+	for i, key := range c.Documents.ID.Keys {
+		if key == "" {
+			problem("documents.id.keys[%d] is empty", i)
+		}
+	}
+	problems = append(problems, c.Documents.Timestamp.validate()...)
+	if c.Documents.Timestamp.Source != TimestampSourceAttribute {
+		// with the creation time of each record, changes and deletes resolve to the index of the month,
+		// day or hour they were made rather than the one holding the document
+		for _, index := range c.itemIndices() {
+			if strings.Contains(index, "{") {
+				problem("documents.timestamp.source must be %s with index %s, which has date patterns", TimestampSourceAttribute, index)
+			}
+		}
+	}
+	problems = append(problems, c.Documents.Routing.validate()...)
+	problems = append(problems, c.Documents.Join.validate(c.Documents.Routing)...)
+	problems = append(problems, c.Documents.TTL.validate()...)
+
+	validateModes := func(field, def string, overrides []Override, modes ...string) {
+		valid := func(mode string) bool {
+			for _, m := range modes {
+				if mode == m {
+					return true
+				}
+			}
+			return false
+		}
+		if !valid(def) {
+			problem("%s.default %q must be one of %s", field, def, strings.Join(modes, ", "))
+		}
+		for i, override := range overrides {
+			if err := override.Path.Validate(); err != nil {
+				problem("%s.overrides[%d].path: %s", field, i, err)
+			}
+			if !valid(override.As) {
+				problem("%s.overrides[%d].as %q must be one of %s", field, i, override.As, strings.Join(modes, ", "))
+			}
+		}
+	}
+	validateModes("documents.numbers", c.Documents.Numbers.Default, c.Documents.Numbers.Overrides,
+		NumbersTyped, NumbersString)
+	validateModes("documents.binary", c.Documents.Binary.Default, c.Documents.Binary.Overrides,
+		BinaryDrop, BinaryBase64, BinaryHex, BinarySHA256, BinarySize)
+
+	for i, pattern := range c.Documents.Include {
+		if err := pattern.Validate(); err != nil {
+			problem("documents.include[%d]: %s", i, err)
+		}
+	}
+	for i, pattern := range c.Documents.Exclude {
+		if err := pattern.Validate(); err != nil {
+			problem("documents.exclude[%d]: %s", i, err)
+		}
+	}
+
+	problems = append(problems, c.Cutover.validate()...)
+
+	if c.Errors.DeadLetter.URL != "" {
+		if err := deadletter.ValidateURL(c.Errors.DeadLetter.URL); err != nil {
+			problem("errors.deadLetter.url: %s", err)
+		}
+	}
+
+	return problems
+}
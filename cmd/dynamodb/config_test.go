@@ -0,0 +1,192 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	config := defaultConfig()
+	assert.Equal(t, "http://localhost:9200", config.Elasticsearch.URL)
+	assert.Equal(t, 500*time.Millisecond, config.Elasticsearch.Retry.InitialBackoff)
+	assert.Equal(t, "|", config.Documents.ID.Separator)
+	assert.Equal(t, []PathPattern{ParsePathPattern("Workflow.workflowDefinition.stateMachine")}, config.Documents.Exclude)
+
+	// the embedded config only lacks the indices, which are always set per deployment
+	config.Elasticsearch.Indices = []string{"index"}
+	assert.Empty(t, config.Validate())
+}
+
+func TestConfigEnvOverrides(t *testing.T) {
+	env := map[string]string{
+		"ELASTICSEARCH_URL":            "https://search.example.com",
+		"ELASTICSEARCH_INDICES":        "index-1, index-2,",
+		"ELASTICSEARCH_USERNAME":       "user",
+		"ELASTICSEARCH_PASSWORD":       "pass",
+		"ELASTICSEARCH_VERSION_TYPE":   "external_gte",
+		"ELASTICSEARCH_VERSION_SOURCE": "creation-time",
+		"FAIL_ON_ERROR":                "true",
+		"DEAD_LETTER_URL":              "s3://bucket/prefix",
+		"DEAD_LETTER_ENDPOINT":         "",
+	}
+	config := defaultConfig()
+	config.Errors.DeadLetter.Endpoint = "http://localhost:4566"
+	config.applyEnv(func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	})
+
+	assert.Equal(t, "https://search.example.com", config.Elasticsearch.URL)
+	assert.Equal(t, []string{"index-1", "index-2"}, config.Elasticsearch.Indices)
+	assert.Equal(t, AuthConfig{Username: "user", Password: "pass", AWS: AWSAuthConfig{Service: "es"}}, config.Elasticsearch.Auth)
+	assert.Equal(t, "external_gte", config.Elasticsearch.VersionType)
+	assert.Equal(t, "creation-time", config.Documents.VersionSource)
+	assert.True(t, config.Errors.FailOnError)
+	assert.Equal(t, "s3://bucket/prefix", config.Errors.DeadLetter.URL)
+	// empty variables don't override
+	assert.Equal(t, "http://localhost:4566", config.Errors.DeadLetter.Endpoint)
+	assert.Empty(t, config.Validate())
+}
+
+func TestConfigValidateReportsAllProblems(t *testing.T) {
+	config, err := parseConfig([]byte(`
+elasticsearch:
+  url: not a url
+  api: v5
+  auth:
+    password: pass
+    apiKey: key
+    aws:
+      sigV4: true
+  tls:
+    certFile: client.pem
+  indices: [""]
+  retry:
+    maxRetries: -1
+    initialBackoff: 2s
+    maxBackoff: 1s
+    jitter: 2
+  bulk:
+    maxBytes: -1
+    concurrency: 0
+  template:
+    file: missing.json
+  writeAliases:
+    enabled: true
+    refreshInterval: 0s
+  documentLimits:
+    policy: truncate
+    maxStringBytes: 0
+  versionType: internal
+  softDeleteIndices: [other]
+documents:
+  exclude:
+    - a..b
+    - a.b*
+errors:
+  deadLetter:
+    url: ftp://example.com/dead-letter
+`))
+	require.NoError(t, err)
+
+	problems := []string{}
+	for _, problem := range config.Validate() {
+		problems = append(problems, problem.Error())
+	}
+	assert.Equal(t, []string{
+		`elasticsearch.url "not a url" is not a valid URL`,
+		`elasticsearch.api "v5" must be v6 or typeless`,
+		"elasticsearch.auth must use only one of username, apiKey or aws.sigV4",
+		"elasticsearch.auth.username is required with a password",
+		"elasticsearch.tls.certFile and keyFile must be set together",
+		"elasticsearch.indices[0] is empty",
+		`elasticsearch.softDeleteIndices[0] "other" is not one of the indices written to`,
+		"elasticsearch.retry.maxRetries must not be negative",
+		"elasticsearch.retry.maxBackoff must not be less than initialBackoff",
+		"elasticsearch.retry.jitter must be between 0 and 1",
+		"elasticsearch.bulk limits must not be negative",
+		"elasticsearch.bulk.concurrency must be at least 1",
+		"elasticsearch.template.file: could not read template: open missing.json: no such file or directory",
+		"elasticsearch.writeAliases.refreshInterval must be positive",
+		"elasticsearch.documentLimits.maxStringBytes must be positive with the truncate policy",
+		`elasticsearch.versionType "internal" must be external or external_gte`,
+		"documents.versionSource and elasticsearch.versionType must be set together",
+		"elasticsearch.softDeleteIndices can't be used with elasticsearch.versionType",
+		`documents.exclude[0]: pattern "a..b" has an empty segment`,
+		`documents.exclude[1]: pattern "a.b*" may only use * and ** as whole segments`,
+		"errors.deadLetter.url: unsupported dead letter URL scheme ftp",
+	}, problems)
+}
+
+func TestConfigValidateIncompatibleOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  func(*Config)
+		problem string
+	}{
+		{
+			name: "creation-time with external",
+			config: func(c *Config) {
+				c.Documents.VersionSource = VersionSourceCreationTime
+				c.Elasticsearch.VersionType = "external"
+			},
+			problem: "documents.versionSource creation-time requires elasticsearch.versionType external_gte",
+		},
+		{
+			name: "writeAliases with partialUpdates",
+			config: func(c *Config) {
+				c.Elasticsearch.WriteAliases.Enabled = true
+				c.Elasticsearch.PartialUpdates = true
+			},
+			problem: "elasticsearch.writeAliases can't be used with elasticsearch.partialUpdates",
+		},
+		{
+			name: "date patterns with the creation time",
+			config: func(c *Config) {
+				c.Elasticsearch.Indices = []string{"events-{yyyy.MM}"}
+			},
+			problem: "documents.timestamp.source must be attribute with index events-{yyyy.MM}, which has date patterns",
+		},
+		{
+			name: "date patterns with an attribute",
+			config: func(c *Config) {
+				c.Elasticsearch.Indices = []string{"events-{yyyy.MM}"}
+				c.Documents.Timestamp = TimestampConfig{Source: TimestampSourceAttribute, Attribute: "createdAt", Format: TimestampFormatRFC3339}
+			},
+		},
+		{
+			name: "creation-time with external_gte",
+			config: func(c *Config) {
+				c.Documents.VersionSource = VersionSourceCreationTime
+				c.Elasticsearch.VersionType = "external_gte"
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := defaultConfig()
+			config.Elasticsearch.Indices = []string{"index"}
+			test.config(&config)
+			problems := []string{}
+			for _, problem := range config.Validate() {
+				problems = append(problems, problem.Error())
+			}
+			if test.problem == "" {
+				assert.Empty(t, problems)
+			} else {
+				assert.Equal(t, []string{test.problem}, problems)
+			}
+		})
+	}
+}
+
+func TestParseConfigRejectsUnknownFields(t *testing.T) {
+	_, err := parseConfig([]byte(`
+elasticsearch:
+  urls: [http://localhost:9200]
+`))
+	assert.Error(t, err)
+}
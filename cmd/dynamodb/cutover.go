@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// CutoverConfig facilitates cutting over to a new DynamoDB stream, such as when a table's
+// replica in another region takes over. Each deployment owns the records created within its
+// windows, so the old and new Lambdas can run in parallel without writing the same change twice.
+type CutoverConfig struct {
+	// Environments limits the cutover to deployments whose DEPLOY_ENV is listed. Applies everywhere if empty.
+	Environments []string `yaml:"environments"`
+	// Windows lists the time windows owned by each region or stream.
+	// Every record is processed if empty.
+	Windows []CutoverWindow `yaml:"windows"`
+}
+
+// CutoverWindow assigns the records created between From and Until to the deployment in Region,
+// or to the Lambda reading the stream with StreamARN
+type CutoverWindow struct {
+	Region    string `yaml:"region"`
+	StreamARN string `yaml:"streamArn"`
+	// From is inclusive and Until is exclusive. Either may be omitted to leave the window open.
+	From  time.Time `yaml:"from"`
+	Until time.Time `yaml:"until"`
+}
+
+// owns returns true if the deployment in region owns the record
+func (w CutoverWindow) owns(region string, record events.DynamoDBEventRecord) bool {
+	if w.Region != "" && w.Region != region {
+		return false
+	}
+	if w.StreamARN != "" && w.StreamARN != record.EventSourceArn {
+		return false
+	}
+	created := record.Change.ApproximateCreationDateTime.Time
+	if !w.From.IsZero() && created.Before(w.From) {
+		return false
+	}
+	if !w.Until.IsZero() && !created.Before(w.Until) {
+		return false
+	}
+	return true
+}
+
+// validate returns every problem with the cutover config
+func (c CutoverConfig) validate() []error {
+	problems := []error{}
+	for i, w := range c.Windows {
+		if (w.Region == "") == (w.StreamARN == "") {
+			problems = append(problems, fmt.Errorf("cutover.windows[%d] must set one of region or streamArn", i))
+		}
+		if !w.From.IsZero() && !w.Until.IsZero() && !w.From.Before(w.Until) {
+			problems = append(problems, fmt.Errorf("cutover.windows[%d].from must be before until", i))
+		}
+	}
+	return problems
+}
+
+// skipRecord returns true if the record is owned by another deployment, according to the cutover windows
+func skipRecord(record events.DynamoDBEventRecord) (bool, error) {
+	cutover := Conf.Cutover
+	if len(cutover.Windows) == 0 {
+		return false, nil
+	}
+	if len(cutover.Environments) > 0 && !contains(cutover.Environments, os.Getenv("DEPLOY_ENV")) {
+		return false, nil
+	}
+
+	region, hasRegion := os.LookupEnv("POD_REGION")
+	for _, w := range cutover.Windows {
+		if w.Region != "" && !hasRegion {
+			return false, errors.New("missing POD_REGION")
+		}
+		if w.owns(region, record) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
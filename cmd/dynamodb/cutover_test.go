@@ -0,0 +1,127 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+// setenv sets an environment variable for the rest of the test
+func setenv(t *testing.T, key, value string) {
+	prev, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestSkipRecord(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	cutover := time.Date(2024, 7, 16, 22, 0, 0, 0, time.UTC)
+	record := func(created time.Time, arn string) events.DynamoDBEventRecord {
+		return events.DynamoDBEventRecord{
+			EventSourceArn: arn,
+			Change: events.DynamoDBStreamRecord{
+				ApproximateCreationDateTime: events.SecondsEpochTime{Time: created},
+			},
+		}
+	}
+	before := record(cutover.Add(-time.Second), "arn:old")
+	at := record(cutover, "arn:new")
+	after := record(cutover.Add(time.Second), "arn:new")
+
+	tests := []struct {
+		name    string
+		env     string
+		region  string
+		cutover CutoverConfig
+		skipped []bool
+	}{
+		{
+			name:    "no windows",
+			region:  "us-west-1",
+			skipped: []bool{false, false, false},
+		},
+		{
+			name:   "old region",
+			env:    "production",
+			region: "us-west-1",
+			cutover: CutoverConfig{Windows: []CutoverWindow{
+				{Region: "us-west-1", Until: cutover},
+				{Region: "us-west-2", From: cutover},
+			}},
+			skipped: []bool{false, true, true},
+		},
+		{
+			name:   "new region",
+			env:    "production",
+			region: "us-west-2",
+			cutover: CutoverConfig{Windows: []CutoverWindow{
+				{Region: "us-west-1", Until: cutover},
+				{Region: "us-west-2", From: cutover},
+			}},
+			skipped: []bool{true, false, false},
+		},
+		{
+			name:   "region without a window",
+			region: "us-east-1",
+			cutover: CutoverConfig{Windows: []CutoverWindow{
+				{Region: "us-west-1", Until: cutover},
+			}},
+			skipped: []bool{true, true, true},
+		},
+		{
+			name:   "other environment",
+			env:    "staging",
+			region: "us-west-1",
+			cutover: CutoverConfig{
+				Environments: []string{"production"},
+				Windows:      []CutoverWindow{{Region: "us-west-1", Until: cutover}},
+			},
+			skipped: []bool{false, false, false},
+		},
+		{
+			name:   "stream ARNs",
+			region: "us-west-1",
+			cutover: CutoverConfig{Windows: []CutoverWindow{
+				{StreamARN: "arn:old", Until: cutover},
+				{StreamARN: "arn:new", From: cutover.Add(time.Second)},
+			}},
+			skipped: []bool{false, true, false},
+		},
+	}
+
+	for _, test := range tests {
+		setenv(t, "DEPLOY_ENV", test.env)
+		setenv(t, "POD_REGION", test.region)
+		Conf.Cutover = test.cutover
+		for i, r := range []events.DynamoDBEventRecord{before, at, after} {
+			skip, err := skipRecord(r)
+			assert.NoError(t, err, test.name)
+			assert.Equal(t, test.skipped[i], skip, "%s: record %d", test.name, i)
+		}
+	}
+
+	os.Unsetenv("POD_REGION")
+	Conf.Cutover = CutoverConfig{Windows: []CutoverWindow{{Region: "us-west-1", Until: cutover}}}
+	_, err := skipRecord(before)
+	assert.Error(t, err)
+}
+
+func TestCutoverValidate(t *testing.T) {
+	now := time.Now()
+	problems := CutoverConfig{Windows: []CutoverWindow{
+		{Region: "us-west-1", Until: now},
+		{From: now},
+		{Region: "us-west-2", StreamARN: "arn", From: now},
+		{Region: "us-west-2", From: now, Until: now},
+	}}.validate()
+	assert.Len(t, problems, 3)
+}
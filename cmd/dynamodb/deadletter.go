@@ -0,0 +1,106 @@
+package main
+
+import (
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gopkg.in/Clever/kayvee-go.v6/logger"
+
+	"github.com/Clever/ddb-to-es/deadletter"
+	"github.com/Clever/ddb-to-es/es"
+)
+
+// sendToDeadLetter sends docs that Elasticsearch permanently rejected to DeadLetter, so they don't
+// block the rest of the stream. It returns the position in docs of every doc that still failed,
+// which includes all failed docs if there is no DeadLetter or it could not be written to.
+// positions holds the position in records of each doc.
+func sendToDeadLetter(records []events.DynamoDBEventRecord, docs []es.Doc, positions []int, result es.WriteResult) []int {
+	failed := result.Failed()
+	if DeadLetter == nil || len(failed) == 0 {
+		return failed
+	}
+
+	now := time.Now()
+	entries := []deadletter.Entry{}
+	stillFailed := []int{}
+	for _, i := range failed {
+		if isRetryable(result.Docs[i]) {
+			stillFailed = append(stillFailed, i)
+			continue
+		}
+		for _, index := range result.Docs[i].Indices {
+			if !index.Failed() {
+				continue
+			}
+			entries = append(entries, deadletter.Entry{
+				Record:    records[positions[i]],
+				Doc:       docs[i],
+				Index:     index.Index,
+				Status:    index.Status,
+				ErrorType: index.ErrorType,
+				Reason:    index.Reason,
+				Time:      now,
+			})
+		}
+	}
+
+	if len(entries) == 0 {
+		return stillFailed
+	}
+	if err := DeadLetter.Send(entries); err != nil {
+		log.ErrorD("dead-letter-failed", logger.M{
+			"error":   err.Error(),
+			"entries": len(entries),
+		})
+		return failed
+	}
+	log.InfoD("dead-letter-sent", logger.M{
+		"entries": len(entries),
+	})
+	return stillFailed
+}
+
+// conversionErrorType is the error type of dead letter entries for records that could not be
+// converted to documents, such as those missing a key attribute
+const conversionErrorType = "record_conversion_exception"
+
+// sendUnconvertedToDeadLetter sends records that could not be converted to documents to DeadLetter,
+// as converting them again would fail the same way. errs holds the error of each record. It returns
+// false if there is no DeadLetter or it could not be written to.
+func sendUnconvertedToDeadLetter(records []events.DynamoDBEventRecord, errs []error) bool {
+	if DeadLetter == nil {
+		return false
+	}
+
+	now := time.Now()
+	entries := []deadletter.Entry{}
+	for i, record := range records {
+		entries = append(entries, deadletter.Entry{
+			Record:    record,
+			ErrorType: conversionErrorType,
+			Reason:    errs[i].Error(),
+			Time:      now,
+		})
+	}
+	if err := DeadLetter.Send(entries); err != nil {
+		log.ErrorD("dead-letter-failed", logger.M{
+			"error":   err.Error(),
+			"entries": len(entries),
+		})
+		return false
+	}
+	log.InfoD("dead-letter-sent", logger.M{
+		"entries": len(entries),
+	})
+	return true
+}
+
+// isRetryable returns true if any of the doc's failed writes may succeed if tried again
+func isRetryable(result es.DocResult) bool {
+	for _, index := range result.Indices {
+		if index.Failed() && index.Retryable {
+			return true
+		}
+	}
+	return false
+}
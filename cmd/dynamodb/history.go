@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/Clever/ddb-to-es/es"
+)
+
+// Change is a change to the attribute at Path. Values are JSON encoded, so that attributes of
+// different types don't conflict in the history index's mapping.
+type Change struct {
+	Path string `json:"path"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// toHistoryDoc returns the document recording the changes a record made to doc's item, written to
+// elasticsearch.historyIndex. Its ID is unique to the record so retries don't record a change twice.
+func toHistoryDoc(record events.DynamoDBEventRecord, doc es.Doc) (es.Doc, error) {
+	added, removed, changed := []Change{}, []Change{}, []Change{}
+	err := diffPaths("", toImage(record.Change.OldImage), toImage(record.Change.NewImage), func(path string, old, new interface{}) error {
+		change := Change{Path: path}
+		if old != nil {
+			encoded, err := json.Marshal(old)
+			if err != nil {
+				return err
+			}
+			change.Old = string(encoded)
+		}
+		if new != nil {
+			encoded, err := json.Marshal(new)
+			if err != nil {
+				return err
+			}
+			change.New = string(encoded)
+		}
+		switch {
+		case old == nil:
+			added = append(added, change)
+		case new == nil:
+			removed = append(removed, change)
+		default:
+			changed = append(changed, change)
+		}
+		return nil
+	})
+	if err != nil {
+		return es.Doc{}, err
+	}
+
+	return es.Doc{
+		Op: es.OpTypeInsert,
+		ID: fmt.Sprintf("%s@%s", doc.ID, record.Change.SequenceNumber),
+		Item: map[string]interface{}{
+			"id":             doc.ID,
+			"event":          record.EventName,
+			"time":           record.Change.ApproximateCreationDateTime.Time,
+			"sequenceNumber": record.Change.SequenceNumber,
+			"added":          added,
+			"removed":        removed,
+			"changed":        changed,
+		},
+		Timestamp: doc.Timestamp,
+		Indices:   []string{Conf.Elasticsearch.HistoryIndex},
+	}, nil
+}
+
+// diffPaths calls fn with the dotted path, old and new value of every attribute that differs between
+// old and new, in path order. Objects are compared attribute by attribute, while anything else is
+// compared whole. A nil old or new value means the attribute was added or removed.
+func diffPaths(prefix string, old, new map[string]interface{}, fn func(path string, old, new interface{}) error) error {
+	keys := []string{}
+	for k := range old {
+		keys = append(keys, k)
+	}
+	for k := range new {
+		if _, ok := old[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		prev, v := old[k], new[k]
+		prevMap, prevIsMap := prev.(map[string]interface{})
+		vMap, vIsMap := v.(map[string]interface{})
+		if prevIsMap && vIsMap {
+			if err := diffPaths(path, prevMap, vMap, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if reflect.DeepEqual(prev, v) {
+			continue
+		}
+		if err := fn(path, prev, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
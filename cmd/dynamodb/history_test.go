@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Clever/ddb-to-es/es"
+)
+
+func TestToHistoryDoc(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	Conf.Elasticsearch.HistoryIndex = "history-{yyyy}"
+	created := time.Unix(1480642020, 0)
+	record := events.DynamoDBEventRecord{
+		EventName: "MODIFY",
+		Change: events.DynamoDBStreamRecord{
+			ApproximateCreationDateTime: events.SecondsEpochTime{Time: created},
+			SequenceNumber:              "123",
+			OldImage: map[string]events.DynamoDBAttributeValue{
+				"name":    events.NewStringAttribute("old"),
+				"removed": events.NewBooleanAttribute(true),
+				"nested": events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{
+					"same":  events.NewStringAttribute("a"),
+					"count": events.NewNumberAttribute("1"),
+				}),
+			},
+			NewImage: map[string]events.DynamoDBAttributeValue{
+				"name":  events.NewStringAttribute("new"),
+				"added": events.NewListAttribute([]events.DynamoDBAttributeValue{events.NewStringAttribute("x")}),
+				"nested": events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{
+					"same":  events.NewStringAttribute("a"),
+					"count": events.NewNumberAttribute("2"),
+				}),
+			},
+		},
+	}
+
+	doc, err := toHistoryDoc(record, es.Doc{ID: "item", Timestamp: created})
+	require.NoError(t, err)
+	assert.Equal(t, es.Doc{
+		Op: es.OpTypeInsert,
+		ID: "item@123",
+		Item: map[string]interface{}{
+			"id":             "item",
+			"event":          "MODIFY",
+			"time":           created,
+			"sequenceNumber": "123",
+			"added":          []Change{{Path: "added", New: `["x"]`}},
+			"removed":        []Change{{Path: "removed", Old: "true"}},
+			"changed": []Change{
+				{Path: "name", Old: `"old"`, New: `"new"`},
+				{Path: "nested.count", Old: "1", New: "2"},
+			},
+		},
+		Timestamp: created,
+		Indices:   []string{"history-{yyyy}"},
+	}, doc)
+}
+
+func TestProcessRecordsHistory(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	Conf.Elasticsearch.HistoryIndex = "history"
+	records := loadDynamoDBEvent(t).Records
+
+	docs, err := processRecords(records, &MockDB{})
+	require.NoError(t, err)
+	require.Len(t, docs, 2*len(records))
+	for i := 0; i < len(records); i++ {
+		assert.Equal(t, docs[2*i].ID+"@"+records[i].Change.SequenceNumber, docs[2*i+1].ID)
+		assert.Equal(t, []string{"history"}, docs[2*i+1].Indices)
+	}
+}
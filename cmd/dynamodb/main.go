@@ -9,46 +9,84 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"gopkg.in/Clever/kayvee-go.v6/logger"
 
+	"github.com/Clever/ddb-to-es/deadletter"
 	"github.com/Clever/ddb-to-es/es"
 )
 
-//go:generate $PWD/bin/go-bindata -pkg $GOPACKAGE -o bindata.go kvconfig.yml
+//go:generate $PWD/bin/go-bindata -pkg $GOPACKAGE -o bindata.go kvconfig.yml config.yml template.json
 //go:generate gofmt -w bindata.go
 
 var log = logger.New(os.Getenv("APP_NAME"))
 
-// FailOnError specifies if to only log errors or to also return to lambda
 var (
-	FailOnError bool
-	DBClient    es.DB
+	// Conf is the loaded configuration. It defaults to the embedded config.yml.
+	Conf     = defaultConfig()
+	DBClient es.DB
+	// DeadLetter receives documents Elasticsearch permanently rejects. Disabled if nil.
+	DeadLetter deadletter.Sink
 )
 
-// ErrNoRecords is an example error you could generate in handling an event.
-var ErrNoRecords = errors.New("no records contained in event")
+var (
+	// ErrNoRecords is an example error you could generate in handling an event.
+	ErrNoRecords = errors.New("no records contained in event")
+	// ErrAllRecordsSkipped is returned when every record in the event belongs to another stream's cutover window
+	ErrAllRecordsSkipped = errors.New("all records skipped for stream cutover")
+)
+
+// RecordsError reports that the records from position Index onwards could not be written
+type RecordsError struct {
+	Index int
+	Err   error
+}
+
+func (e *RecordsError) Error() string {
+	return fmt.Sprintf("record %d: %s", e.Index, e.Err)
+}
+
+func (e *RecordsError) Unwrap() error {
+	return e.Err
+}
 
 // Handler is your Lambda function handler.
-// The return signature can be empty, a single error, or a return value (struct or string) and error.
-func Handler(ctx context.Context, event events.DynamoDBEvent) error {
-	if _, err := processRecords(event.Records, DBClient); err != nil {
-		if FailOnError {
-			return err
-		}
-		errorMsg := err.Error()
-		if len(errorMsg) > 50 {
-			errorMsg = errorMsg[:50]
+// Records that fail to be written are reported back to Lambda as batch item failures,
+// along with every record after them so that per-shard ordering is kept on retry.
+func Handler(ctx context.Context, event events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+	response := events.DynamoDBEventResponse{}
+	_, err := processRecords(event.Records, DBClient)
+	if err == nil || err == ErrAllRecordsSkipped {
+		return response, nil
+	}
+
+	// unless we know which record failed, retry the whole batch
+	first := 0
+	var recordsErr *RecordsError
+	if errors.As(err, &recordsErr) {
+		first = recordsErr.Index
+	}
+
+	errorMsg := err.Error()
+	if len(errorMsg) > 50 {
+		errorMsg = errorMsg[:50]
+	}
+	log.ErrorD("process-records-failure", logger.M{
+		"error":          errorMsg,
+		"failed-records": len(event.Records) - first,
+	})
+
+	if Conf.Errors.FailOnError {
+		for _, record := range event.Records[first:] {
+			response.BatchItemFailures = append(response.BatchItemFailures, events.DynamoDBBatchItemFailure{
+				ItemIdentifier: record.Change.SequenceNumber,
+			})
 		}
-		log.ErrorD("process-records-failure", logger.M{
-			"error": errorMsg,
-		})
 	}
 
-	return nil
+	return response, nil
 }
 
 func main() {
@@ -57,26 +95,35 @@ func main() {
 		log.ErrorD("kvconfig-err", logger.M{"error": err})
 		os.Exit(1)
 	}
-	if FailOnError, err = strconv.ParseBool(os.Getenv("FAIL_ON_ERROR")); err != nil {
-		FailOnError = false
-	}
 
-	// Parse a comma separated list of Elasticsearch indices.
-	rawESIndices := strings.Split(os.Getenv("ELASTICSEARCH_INDICES"), ",")
-	esIndices := []string{}
-	for _, index := range rawESIndices {
-		if index != "" {
-			esIndices = append(esIndices, strings.TrimSpace(index))
-		}
+	Conf, err = loadConfig()
+	if err != nil {
+		log.ErrorD("config-err", logger.M{"error": err.Error()})
+		os.Exit(1)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(validate(Conf))
 	}
-	if len(esIndices) < 1 {
-		log.Error("missing-elasticsearch-indices")
+	if problems := Conf.Validate(); len(problems) > 0 {
+		for _, problem := range problems {
+			log.ErrorD("invalid-config", logger.M{"error": problem.Error()})
+		}
 		os.Exit(1)
 	}
-	esURL := os.Getenv("ELASTICSEARCH_URL")
 
-	dbConfig := &es.DBConfig{URL: esURL}
-	DBClient, err = es.NewDB(dbConfig, esIndices, log)
+	command := ""
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+
+	dbConfig := Conf.Elasticsearch.DBConfig()
+	if Conf.Elasticsearch.Template.Bootstrap && command != "bootstrap" {
+		if dbConfig.Bootstrap, err = bootstrapConfig(); err != nil {
+			log.ErrorD("bootstrap-config-error", logger.M{"error": err.Error()})
+			os.Exit(1)
+		}
+	}
+	DBClient, err = es.Open(dbConfig, Conf.Elasticsearch.Indices, log)
 	if err != nil {
 		log.ErrorD("elasticsearch-connect-error", logger.M{
 			"message": err.Error(),
@@ -85,6 +132,25 @@ func main() {
 		os.Exit(1)
 	}
 
+	if Conf.Errors.DeadLetter.URL != "" {
+		DeadLetter, err = deadletter.New(Conf.Errors.DeadLetter.URL, Conf.Errors.DeadLetter.Endpoint)
+		if err != nil {
+			log.ErrorD("dead-letter-error", logger.M{"message": err.Error()})
+			os.Exit(1)
+		}
+	}
+
+	switch command {
+	case "replay":
+		os.Exit(replay(os.Args[2:]))
+	case "bootstrap":
+		os.Exit(bootstrap(DBClient))
+	case "reindex":
+		os.Exit(reindex(DBClient, os.Args[2:]))
+	case "backfill":
+		os.Exit(backfill(os.Args[2:]))
+	}
+
 	if os.Getenv("POD_REGION") == "local" {
 		event := events.DynamoDBEvent{
 			Records: []events.DynamoDBEventRecord{
@@ -95,111 +161,242 @@ func main() {
 			"data": event,
 		})
 
-		err := Handler(context.Background(), event)
+		response, err := Handler(context.Background(), event)
 		if err != nil {
 			log.ErrorD("failed-local-run", logger.M{"error": err.Error()})
 			os.Exit(1)
 		}
+		if len(response.BatchItemFailures) > 0 {
+			log.ErrorD("failed-local-run", logger.M{"failures": response.BatchItemFailures})
+			os.Exit(1)
+		}
 	} else {
 		lambda.Start(Handler)
 	}
 }
 
-const cutoverTime = "2024-07-16T15:00:00-07:00"
+// processRecords converts DynamoDB stream records to es.Doc and writes them to the db.
+// If some of the records could not be converted or written a *RecordsError is returned
+// identifying the first of them.
+func processRecords(records []events.DynamoDBEventRecord, db es.DB) ([]es.Doc, error) {
+	if len(records) == 0 {
+		return nil, ErrNoRecords
+	}
 
-// Facilitates cutting over to a new DDB stream. Before the time all
-// records will be processed in uw1. After the time, all records will be
-// processed in uw2. Making the assumption that it is extremely unlikely
-// that a record will have an exact timestamp down to the nanosecond of
-// the cutover time, so skipping that edge case. If you are reading this
-// after the cutover time you can safely revert
-// https://github.com/Clever/ddb-to-es/pull/78
-func skipRecord(record events.DynamoDBEventRecord) (bool, error) {
-	// only do this in prod and clever-dev
-	env := os.Getenv("DEPLOY_ENV")
-	if env != "production" && env != "clever-dev" {
-		return false, nil
+	docs := []es.Doc{}
+	// position in records of each doc
+	positions := []int{}
+	// position in records, and error, of each record that could not be converted
+	unconverted := []int{}
+	convertErrs := []error{}
+	// TODO: we can parallalize this
+	for i, record := range records {
+		recordDocs, err := toDocs(record)
+		if err != nil {
+			unconverted = append(unconverted, i)
+			convertErrs = append(convertErrs, err)
+			continue
+		}
+		for _, doc := range recordDocs {
+			docs = append(docs, doc)
+			positions = append(positions, i)
+		}
 	}
 
-	t, err := time.Parse(time.RFC3339, cutoverTime)
-	if err != nil {
-		return false, fmt.Errorf("failed to parse cutover time: %s", err)
+	// records that could not be converted never will be, so they're dead lettered like rejected docs.
+	// Otherwise the first of them fails, and only the docs before it are written.
+	var convertErr *RecordsError
+	if len(unconverted) > 0 {
+		unconvertedRecords := []events.DynamoDBEventRecord{}
+		for _, i := range unconverted {
+			unconvertedRecords = append(unconvertedRecords, records[i])
+		}
+		if !sendUnconvertedToDeadLetter(unconvertedRecords, convertErrs) {
+			first := unconverted[0]
+			convertErr = &RecordsError{Index: first, Err: convertErrs[0]}
+			for len(positions) > 0 && positions[len(positions)-1] > first {
+				docs, positions = docs[:len(docs)-1], positions[:len(positions)-1]
+			}
+		}
 	}
-	r, ok := os.LookupEnv("POD_REGION")
-	if !ok {
-		return false, errors.New("missing POD_REGION")
+
+	if len(docs) == 0 {
+		if convertErr != nil {
+			return nil, convertErr
+		}
+		return nil, ErrAllRecordsSkipped
 	}
 
-	if r == "us-west-2" && record.Change.ApproximateCreationDateTime.After(t) {
-		return false, nil
+	result, err := db.WriteDocs(docs)
+	if err != nil {
+		return nil, &RecordsError{Index: positions[0], Err: err}
+	}
+	if failed := sendToDeadLetter(records, docs, positions, result); len(failed) > 0 {
+		first := failed[0]
+		return docs[:first], &RecordsError{
+			Index: positions[first],
+			Err:   fmt.Errorf("%d of %d documents failed to write", len(failed), len(docs)),
+		}
 	}
-	if r == "us-west-1" && record.Change.ApproximateCreationDateTime.Before(t) {
-		return false, nil
+	if convertErr != nil {
+		return docs, convertErr
 	}
-	return true, nil
+
+	return docs, nil
 }
 
-// processRecords converts DynamoDB stream records to es.Doc and writes them to the db
-func processRecords(records []events.DynamoDBEventRecord, db es.DB) ([]es.Doc, error) {
-	if len(records) == 0 {
-		return nil, ErrNoRecords
+// toDocs converts a DynamoDB stream record to every es.Doc written for it: its document, followed
+// by its change history and archived copy if enabled. It returns no docs if the record should not be written.
+func toDocs(record events.DynamoDBEventRecord) ([]es.Doc, error) {
+	doc, ok, err := toDoc(record)
+	if err != nil || !ok {
+		return nil, err
 	}
-
-	docs := []es.Doc{}
-	// TODO: we can parallalize this
-	for _, record := range records {
-		skip, err := skipRecord(record)
+	docs := []es.Doc{doc}
+	moved, ok, err := toMovedDoc(record, doc)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		// deleted from the indices it's moved out of before it's written to the new ones
+		docs = []es.Doc{moved, doc}
+	}
+	if Conf.Elasticsearch.HistoryIndex != "" {
+		history, err := toHistoryDoc(record, doc)
 		if err != nil {
 			return nil, err
 		}
-		if skip {
-			continue
-		}
-		id, err := toId(record.Change.Keys)
+		docs = append(docs, history)
+	}
+	if doc.Expired && Conf.Documents.TTL.Policy == TTLPolicyArchive {
+		docs = append(docs, toArchiveDoc(doc))
+	}
+	return docs, nil
+}
+
+// toDoc converts a DynamoDB stream record to an es.Doc.
+// ok is false if the record should not be written.
+func toDoc(record events.DynamoDBEventRecord) (doc es.Doc, ok bool, err error) {
+	skip, err := skipRecord(record)
+	if err != nil || skip {
+		return es.Doc{}, false, err
+	}
+	id, err := toId(record.Change.Keys)
+	if err != nil {
+		return es.Doc{}, false, err
+	}
+	version, err := toVersion(record)
+	if err != nil {
+		return es.Doc{}, false, err
+	}
+	timestamp, err := toTimestamp(record)
+	if err != nil {
+		return es.Doc{}, false, err
+	}
+	indices, ok := toIndices(record)
+	if !ok {
+		return es.Doc{}, false, nil
+	}
+	routing, err := toRouting(record)
+	if err != nil {
+		return es.Doc{}, false, err
+	}
+	image := record.Change.NewImage
+	if events.DynamoDBOperationType(record.EventName) == events.DynamoDBOperationTypeRemove {
+		// the last known image, kept by soft deletes
+		image = record.Change.OldImage
+	}
+	item := toImage(image)
+	if len(item) > 0 {
+		join, err := toJoin(record)
 		if err != nil {
-			return nil, err
+			return es.Doc{}, false, err
 		}
-		item := map[string]interface{}{}
-		for k, v := range record.Change.NewImage {
-			if i := toItem(v, k); i != nil {
-				item[santizeKey(k)] = i
-			}
-		}
-		switch events.DynamoDBOperationType(record.EventName) {
-		case events.DynamoDBOperationTypeInsert:
-			docs = append(docs, es.Doc{Op: es.OpTypeInsert, ID: id, Item: item})
-		case events.DynamoDBOperationTypeModify:
-			docs = append(docs, es.Doc{Op: es.OpTypeUpdate, ID: id, Item: item})
-		case events.DynamoDBOperationTypeRemove:
-			docs = append(docs, es.Doc{Op: es.OpTypeDelete, ID: id, Item: item})
-		case "":
-			continue
-		default:
-			return nil, fmt.Errorf("Unsupported eventName %s", record.EventName)
+		if join != nil {
+			item[Conf.Documents.Join.Field] = join
 		}
 	}
+	doc = es.Doc{ID: id, Item: item, Version: version, Timestamp: timestamp, Indices: indices, Routing: routing}
 
-	if len(docs) == 0 {
-		return nil, errors.New("all records skipped for stream cutover")
+	switch events.DynamoDBOperationType(record.EventName) {
+	case events.DynamoDBOperationTypeInsert:
+		doc.Op = es.OpTypeInsert
+	case events.DynamoDBOperationTypeModify:
+		doc.Op = es.OpTypeUpdate
+		moved, _, err := toMovedIndices(record, doc)
+		if err != nil {
+			return es.Doc{}, false, err
+		}
+		if Conf.Elasticsearch.PartialUpdates && record.Change.OldImage != nil && len(moved) == 0 {
+			// only send what changed, so fields other pipelines add to the document are kept.
+			// A document moved to another index is written whole, as it isn't there yet.
+			doc.Item = toChanges(toImage(record.Change.OldImage), item)
+			doc.Partial = true
+		}
+	case events.DynamoDBOperationTypeRemove:
+		doc.Op = es.OpTypeDelete
+		doc.DeletedAt = record.Change.ApproximateCreationDateTime.Time
+		doc.Expired = isExpired(record)
+		if doc.Expired {
+			switch Conf.Documents.TTL.Policy {
+			case TTLPolicyKeep:
+				return es.Doc{}, false, nil
+			case TTLPolicyTombstone:
+				doc.SoftDelete = true
+			}
+		}
+	case "":
+		return es.Doc{}, false, nil
+	default:
+		return es.Doc{}, false, fmt.Errorf("Unsupported eventName %s", record.EventName)
 	}
+	return doc, true, nil
+}
 
-	if err := db.WriteDocs(docs); err != nil {
-		return nil, err
+// toImage converts a DynamoDB image to a document's item
+func toImage(image map[string]events.DynamoDBAttributeValue) map[string]interface{} {
+	item := map[string]interface{}{}
+	for k, v := range image {
+		if i := toAttribute(v, k); i != nil {
+			item[santizeKey(k)] = i
+		}
 	}
-
-	return docs, nil
+	return item
 }
 
 // toId generates a deterministic Id for each record
 func toId(ddbKeys map[string]events.DynamoDBAttributeValue) (string, error) {
 	values := []string{}
-	keysSorted := []string{}
-	for k := range ddbKeys {
-		keysSorted = append(keysSorted, k)
+	keysSorted := Conf.Documents.ID.Keys
+	if len(keysSorted) == 0 {
+		for k := range ddbKeys {
+			keysSorted = append(keysSorted, k)
+		}
+		sort.Strings(keysSorted)
 	}
-	sort.Strings(keysSorted)
 	for _, k := range keysSorted {
-		key := ddbKeys[k]
+		key, ok := ddbKeys[k]
+		if !ok {
+			return "", fmt.Errorf("missing key attribute %s", k)
+		}
+		if key.DataType() == events.DataTypeNumber {
+			// numbers are equal regardless of how they are written, so their ID should be too
+			number, _, err := canonicalNumber(key.Number())
+			if err != nil {
+				return "", err
+			}
+			values = append(values, number)
+			continue
+		}
+		if key.DataType() == events.DataTypeBinary {
+			// IDs don't depend on how binary attributes are configured to be indexed
+			val, err := json.Marshal(key.Binary())
+			if err != nil {
+				return "", err
+			}
+			values = append(values, string(val))
+			continue
+		}
 		item := toItem(key, "")
 		if key.DataType() == events.DataTypeMap ||
 			key.DataType() == events.DataTypeList ||
@@ -215,8 +412,6 @@ func toId(ddbKeys map[string]events.DynamoDBAttributeValue) (string, error) {
 			values = append(values, string(val[:]))
 		} else {
 			switch item.(type) {
-			// case int:
-			// TODO: aws-sdk-go treats number as string
 			case string:
 				values = append(values, item.(string))
 			case bool:
@@ -225,7 +420,7 @@ func toId(ddbKeys map[string]events.DynamoDBAttributeValue) (string, error) {
 		}
 	}
 
-	return strings.Join(values, "|"), nil
+	return strings.Join(values, Conf.Documents.ID.Separator), nil
 }
 
 // toItem recursively walks through DynamoDBAttributeValue
@@ -234,7 +429,12 @@ func toItem(value events.DynamoDBAttributeValue, pathSoFar string) interface{} {
 	switch value.DataType() {
 	case events.DataTypeList:
 		doc := []interface{}{}
+		// when only some attributes nested in the list are included, other elements are dropped
+		partial := !isIncluded(pathSoFar)
 		for _, item := range value.List() {
+			if partial && item.DataType() != events.DataTypeMap && item.DataType() != events.DataTypeList {
+				continue
+			}
 			if i := toItem(item, pathSoFar); i != nil {
 				doc = append(doc, i)
 			}
@@ -244,11 +444,7 @@ func toItem(value events.DynamoDBAttributeValue, pathSoFar string) interface{} {
 		doc := map[string]interface{}{}
 		for k, v := range value.Map() {
 			path := fmt.Sprintf("%s.%s", pathSoFar, k)
-			// When we send workflows to ES, including the state machine explodes the number of fields.
-			if path == "Workflow.workflowDefinition.stateMachine" {
-				continue
-			}
-			if i := toItem(v, path); i != nil {
+			if i := toAttribute(v, path); i != nil {
 				doc[santizeKey(k)] = i
 			}
 		}
@@ -256,15 +452,15 @@ func toItem(value events.DynamoDBAttributeValue, pathSoFar string) interface{} {
 	case events.DataTypeNull:
 		return nil
 	case events.DataTypeNumber:
-		return value.Number()
+		return toNumber(value.Number(), pathSoFar)
 	case events.DataTypeNumberSet:
-		return value.NumberSet()
+		return toNumbers(value.NumberSet(), pathSoFar)
 	case events.DataTypeBinary:
-		return value.Binary()
+		return toBinary(value.Binary(), pathSoFar)
 	case events.DataTypeBoolean:
 		return value.Boolean()
 	case events.DataTypeBinarySet:
-		return value.BinarySet()
+		return toBinaries(value.BinarySet(), pathSoFar)
 	case events.DataTypeString:
 		return value.String()
 	case events.DataTypeStringSet:
@@ -274,6 +470,61 @@ func toItem(value events.DynamoDBAttributeValue, pathSoFar string) interface{} {
 	}
 }
 
+// toAttribute converts the attribute at path, returning nil if it is not indexed
+func toAttribute(value events.DynamoDBAttributeValue, path string) interface{} {
+	if isExcluded(path) {
+		return nil
+	}
+	if isIncluded(path) {
+		return toItem(value, path)
+	}
+	if !mayIncludeNested(path) {
+		return nil
+	}
+
+	// only some of the attributes nested below path are included, so drop it if none of them are present
+	switch item := toItem(value, path).(type) {
+	case map[string]interface{}:
+		if len(item) > 0 {
+			return item
+		}
+	case []interface{}:
+		if len(item) > 0 {
+			return item
+		}
+	}
+	return nil
+}
+
+// isExcluded returns true if the attribute at path is configured to not be indexed
+func isExcluded(path string) bool {
+	return matchAny(Conf.Documents.Exclude, path)
+}
+
+// isIncluded returns true if the attribute at path, or one it is nested in, is allowed to be indexed
+func isIncluded(path string) bool {
+	if len(Conf.Documents.Include) == 0 || path == "" {
+		return true
+	}
+	segments := strings.Split(path, ".")
+	for i := 1; i <= len(segments); i++ {
+		if matchAny(Conf.Documents.Include, strings.Join(segments[:i], ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// mayIncludeNested returns true if attributes nested below path may be allowed to be indexed
+func mayIncludeNested(path string) bool {
+	for _, pattern := range Conf.Documents.Include {
+		if pattern.MatchPrefix(path) {
+			return true
+		}
+	}
+	return false
+}
+
 // santizeKey makes sure that document keys meet Elasticsearch requirements
 func santizeKey(key string) string {
 	if _, ok := es.ESReservedFields[key]; ok {
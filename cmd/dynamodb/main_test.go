@@ -1,19 +1,45 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"testing"
+	"time"
 
+	"github.com/Clever/ddb-to-es/deadletter"
 	"github.com/Clever/ddb-to-es/es"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/stretchr/testify/assert"
 )
 
-type MockDB struct{}
+type MockDB struct {
+	failed    []int
+	retryable bool
+	err       error
+}
 
-func (db *MockDB) WriteDocs(docs []es.Doc) error {
-	return nil
+func (db *MockDB) WriteDocs(docs []es.Doc) (es.WriteResult, error) {
+	if db.err != nil {
+		return es.WriteResult{}, db.err
+	}
+	result := es.WriteResult{}
+	for _, doc := range docs {
+		result.Docs = append(result.Docs, es.DocResult{
+			ID:      doc.ID,
+			Indices: []es.IndexResult{{Index: "test-index", Status: 201}},
+		})
+	}
+	for _, i := range db.failed {
+		result.Docs[i].Indices[0] = es.IndexResult{
+			Index:     "test-index",
+			Status:    400,
+			ErrorType: "mapper_parsing_exception",
+			Retryable: db.retryable,
+		}
+	}
+	return result, nil
 }
 
 func TestProcessRecords(t *testing.T) {
@@ -29,28 +55,29 @@ func TestProcessRecords(t *testing.T) {
 					Op: "insert",
 					ID: "binary|data",
 					Item: map[string]interface{}{
-						"asdf1": []uint8{0x0, 0x1, 0x2a, 0x41},
-						"asdf2": [][]uint8{[]uint8{0x0, 0x1, 0x2a, 0x41}, []uint8{0x41, 0x2a, 0x1, 0x0}},
+						"asdf1": "AAEqQQ==",
+						"asdf2": []interface{}{"AAEqQQ==", "QSoBAA=="},
 						"key":   "binary", "val": "data"},
+					Timestamp: time.Unix(1480642020, 0),
 				},
 				es.Doc{
 					Op: "insert",
 					ID: "binary|data",
 					Item: map[string]interface{}{
-						"Binary":         []uint8{0x0, 0x1, 0x2a, 0x41},
-						"BinarySet":      [][]uint8{[]uint8{0x0, 0x1, 0x2a, 0x41}, []uint8{0x0, 0x1, 0x2a, 0x41}},
+						"Binary":         "AAEqQQ==",
+						"BinarySet":      []interface{}{"AAEqQQ==", "AAEqQQ=="},
 						"Boolean":        true,
 						"EmptyStringSet": []string{},
-						"FloatNumber":    "123.45",
-						"IntegerNumber":  "123",
-						"List":           []interface{}{"Cookies", "Coffee", "3.14159"},
+						"FloatNumber":    123.45,
+						"IntegerNumber":  int64(123),
+						"List":           []interface{}{"Cookies", "Coffee", 3.14159},
 						"Map": map[string]interface{}{
-							"Age":  "35",
+							"Age":  int64(35),
 							"Name": "Joe",
 							"Workflow": map[string]interface{}{
 								"workflowDefinition": map[string]interface{}{
 									"stateMachine": map[string]interface{}{
-										"NumStates": "1",
+										"NumStates": int64(1),
 										"State1":    "state 1",
 									},
 								},
@@ -59,13 +86,14 @@ func TestProcessRecords(t *testing.T) {
 						"Workflow": map[string]interface{}{
 							"workflowDefinition": map[string]interface{}{},
 						},
-						"NumberSet": []string{"1234", "567.8"},
+						"NumberSet": []interface{}{int64(1234), 567.8},
 						"String":    "Hello",
 						"StringSet": []string{"Giraffe", "Zebra"},
-						"asdf1":     []uint8{0x0, 0x1, 0x2a, 0x41},
-						"asdf2":     [][]uint8{[]uint8{0x0, 0x1, 0x2a, 0x41}, []uint8{0x41, 0x2a, 0x1, 0x0}, []uint8{0x0, 0x1, 0x2a, 0x41}},
-						"b2":        []uint8{0xb5, 0xeb, 0x2d}, "key": "binary", "val": "data",
+						"asdf1":     "AAEqQQ==",
+						"asdf2":     []interface{}{"AAEqQQ==", "QSoBAA==", "AAEqQQ=="},
+						"b2":        "test", "key": "binary", "val": "data",
 					},
+					Timestamp: time.Unix(1480642020, 0),
 				},
 			},
 			err: nil,
@@ -85,6 +113,134 @@ func TestProcessRecords(t *testing.T) {
 	}
 }
 
+func TestHandlerBatchItemFailures(t *testing.T) {
+	event := loadDynamoDBEvent(t)
+	event.Records[0].Change.SequenceNumber = "100"
+	event.Records[1].Change.SequenceNumber = "200"
+	unsupported := event.Records[1]
+	unsupported.EventName = "UNKNOWN"
+	unsupported.Change.SequenceNumber = "300"
+
+	tests := []struct {
+		records     []events.DynamoDBEventRecord
+		db          *MockDB
+		failOnError bool
+		failures    []string
+	}{
+		{
+			records:     event.Records,
+			db:          &MockDB{},
+			failOnError: true,
+			failures:    nil,
+		},
+		{
+			records:     event.Records,
+			db:          &MockDB{failed: []int{1}},
+			failOnError: true,
+			failures:    []string{"200"},
+		},
+		{
+			records:     event.Records,
+			db:          &MockDB{err: errors.New("connection refused")},
+			failOnError: true,
+			failures:    []string{"100", "200"},
+		},
+		{
+			records:     []events.DynamoDBEventRecord{event.Records[0], unsupported, event.Records[1]},
+			db:          &MockDB{},
+			failOnError: true,
+			failures:    []string{"300", "200"},
+		},
+		{
+			records:     event.Records,
+			db:          &MockDB{failed: []int{0}},
+			failOnError: false,
+			failures:    nil,
+		},
+	}
+
+	defer func(db es.DB, conf Config) {
+		DBClient, Conf = db, conf
+	}(DBClient, Conf)
+
+	for _, test := range tests {
+		DBClient, Conf.Errors.FailOnError = test.db, test.failOnError
+		resp, err := Handler(context.Background(), events.DynamoDBEvent{Records: test.records})
+		assert.NoError(t, err)
+		var failures []string
+		for _, failure := range resp.BatchItemFailures {
+			failures = append(failures, failure.ItemIdentifier)
+		}
+		assert.Equal(t, test.failures, failures)
+	}
+}
+
+type MockSink struct {
+	entries []deadletter.Entry
+	err     error
+}
+
+func (s *MockSink) Send(entries []deadletter.Entry) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.entries = append(s.entries, entries...)
+	return nil
+}
+
+func TestProcessRecordsDeadLetter(t *testing.T) {
+	defer func(sink deadletter.Sink) { DeadLetter = sink }(DeadLetter)
+	sink := &MockSink{}
+	DeadLetter = sink
+
+	event := loadDynamoDBEvent(t)
+	_, err := processRecords(event.Records, &MockDB{failed: []int{0}})
+	assert.NoError(t, err)
+	if assert.Len(t, sink.entries, 1) {
+		assert.Equal(t, event.Records[0], sink.entries[0].Record)
+		assert.Equal(t, "mapper_parsing_exception", sink.entries[0].ErrorType)
+	}
+
+	sink.entries = nil
+	_, err = processRecords(event.Records, &MockDB{failed: []int{1}, retryable: true})
+	recordsErr := &RecordsError{}
+	if assert.True(t, errors.As(err, &recordsErr)) {
+		assert.Equal(t, 1, recordsErr.Index)
+	}
+	assert.Empty(t, sink.entries)
+}
+
+func TestProcessRecordsDeadLettersUnconvertedRecords(t *testing.T) {
+	defer func(sink deadletter.Sink) { DeadLetter = sink }(DeadLetter)
+	event := loadDynamoDBEvent(t)
+	unsupported := event.Records[1]
+	unsupported.EventName = "UNKNOWN"
+	records := []events.DynamoDBEventRecord{event.Records[0], unsupported, event.Records[1]}
+
+	// records that can't be converted are dead lettered, and the rest written
+	sink := &MockSink{}
+	DeadLetter = sink
+	docs, err := processRecords(records, &MockDB{})
+	assert.NoError(t, err)
+	assert.Len(t, docs, 2)
+	if assert.Len(t, sink.entries, 1) {
+		assert.Equal(t, unsupported, sink.entries[0].Record)
+		assert.Equal(t, "record_conversion_exception", sink.entries[0].ErrorType)
+		assert.Equal(t, "Unsupported eventName UNKNOWN", sink.entries[0].Reason)
+	}
+
+	// without a dead letter sink to send them to, they fail along with the records after them
+	for _, sink := range []deadletter.Sink{nil, &MockSink{err: errors.New("access denied")}} {
+		DeadLetter = sink
+		docs, err = processRecords(records, &MockDB{})
+		recordsErr := &RecordsError{}
+		if assert.True(t, errors.As(err, &recordsErr)) {
+			assert.Equal(t, 1, recordsErr.Index)
+		}
+		assert.Len(t, docs, 1)
+	}
+}
+
 func loadDynamoDBEvent(t *testing.T) events.DynamoDBEvent {
 	// 1. read JSON from file
 	inputJson, err := ioutil.ReadFile("./testdata/dynamodb-event.json")
@@ -100,3 +256,27 @@ func loadDynamoDBEvent(t *testing.T) events.DynamoDBEvent {
 
 	return inputEvent
 }
+
+func TestToVersion(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	record := events.DynamoDBEventRecord{
+		Change: events.DynamoDBStreamRecord{
+			ApproximateCreationDateTime: events.SecondsEpochTime{Time: time.Unix(1480642020, 0)},
+			SequenceNumber:              "1405400000000002063282832",
+		},
+	}
+
+	Conf.Documents.VersionSource = ""
+	version, err := toVersion(record)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, version)
+
+	Conf.Documents.VersionSource = VersionSourceCreationTime
+	version, err = toVersion(record)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1480642020000, version)
+
+	Conf.Documents.VersionSource = "sequence-number"
+	_, err = toVersion(record)
+	assert.Error(t, err)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// How DynamoDB numbers are indexed
+const (
+	// NumbersTyped indexes numbers as JSON integers or floats, so Elasticsearch maps them as numeric
+	// fields. Numbers float64 can't represent without losing precision are indexed as strings.
+	NumbersTyped = "typed"
+	// NumbersString indexes numbers as strings, exactly as DynamoDB stores them
+	NumbersString = "string"
+)
+
+// maxExponent bounds the exponent of numbers. DynamoDB numbers are between 1e-130 and 1e126 in magnitude.
+const maxExponent = 200
+
+// maxFloatDigits is the number of significant decimal digits float64 always represents exactly
+const maxFloatDigits = 15
+
+// numberMode returns how the number at path is indexed
+func numberMode(path string) string {
+	return mode(Conf.Documents.Numbers.Overrides, Conf.Documents.Numbers.Default, path)
+}
+
+// toNumber converts a DynamoDB number at path as configured
+func toNumber(number, path string) interface{} {
+	if numberMode(path) != NumbersTyped {
+		return number
+	}
+	canonical, digits, err := canonicalNumber(number)
+	if err != nil {
+		return number
+	}
+	if !strings.Contains(canonical, ".") {
+		if i, err := strconv.ParseInt(canonical, 10, 64); err == nil {
+			return i
+		}
+	}
+	if digits > maxFloatDigits {
+		return number
+	}
+	f, err := strconv.ParseFloat(canonical, 64)
+	if err != nil {
+		// out of float64's range
+		return number
+	}
+	return f
+}
+
+// toNumbers converts a DynamoDB number set at path as configured
+func toNumbers(numbers []string, path string) interface{} {
+	if numberMode(path) != NumbersTyped {
+		return numbers
+	}
+	converted := make([]interface{}, len(numbers))
+	for i, number := range numbers {
+		converted[i] = toNumber(number, path)
+	}
+	return converted
+}
+
+// canonicalNumber returns the plain decimal representation of a DynamoDB number, without an
+// exponent, leading zeros or trailing fractional zeros, along with its number of significant digits.
+// Numbers that are equal have the same canonical representation.
+func canonicalNumber(number string) (string, int, error) {
+	invalid := fmt.Errorf("invalid number %q", number)
+	s := strings.TrimSpace(number)
+
+	sign := ""
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		if s[0] == '-' {
+			sign = "-"
+		}
+		s = s[1:]
+	}
+
+	exponent := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return "", 0, invalid
+		}
+		exponent, s = e, s[:i]
+	}
+
+	intPart, fracPart := s, ""
+	if i := strings.Index(s, "."); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	digits := intPart + fracPart
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return "", 0, invalid
+	}
+	exponent -= len(fracPart)
+	if exponent > maxExponent || exponent < -maxExponent-len(digits) {
+		return "", 0, invalid
+	}
+
+	// the number is now digits * 10^exponent
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		return "0", 0, nil
+	}
+	trimmed := strings.TrimRight(digits, "0")
+	exponent += len(digits) - len(trimmed)
+	digits = trimmed
+
+	if exponent >= 0 {
+		return sign + digits + strings.Repeat("0", exponent), len(digits), nil
+	}
+	point := len(digits) + exponent
+	if point > 0 {
+		return sign + digits[:point] + "." + digits[point:], len(digits), nil
+	}
+	return sign + "0." + strings.Repeat("0", -point) + digits, len(digits), nil
+}
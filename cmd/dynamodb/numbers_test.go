@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalNumber(t *testing.T) {
+	tests := []struct {
+		number    string
+		canonical string
+		digits    int
+	}{
+		{"123", "123", 3},
+		{"-0123.4500", "-123.45", 5},
+		{"0", "0", 0},
+		{"-0.000", "0", 0},
+		{"1.5E3", "1500", 2},
+		{"+25e-3", "0.025", 2},
+		{".5", "0.5", 1},
+		{"1000", "1000", 1},
+		{"12345678901234567890123456789012345678", "12345678901234567890123456789012345678", 38},
+	}
+	for _, test := range tests {
+		canonical, digits, err := canonicalNumber(test.number)
+		assert.NoError(t, err, test.number)
+		assert.Equal(t, test.canonical, canonical, test.number)
+		assert.Equal(t, test.digits, digits, test.number)
+	}
+
+	for _, invalid := range []string{"", "-", "1e", "abc", "1.2.3", "1e100000"} {
+		_, _, err := canonicalNumber(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestToNumber(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	Conf.Documents.Numbers = NumbersConfig{
+		Default: NumbersTyped,
+		Overrides: []Override{
+			{Path: ParsePathPattern("**.zip"), As: NumbersString},
+		},
+	}
+
+	assert.Equal(t, int64(123), toNumber("123", "count"))
+	assert.Equal(t, int64(-9223372036854775808), toNumber("-9223372036854775808", "count"))
+	assert.Equal(t, 123.45, toNumber("123.45", "price"))
+	assert.Equal(t, 1e20, toNumber("100000000000000000000", "count"))
+	// float64 can't hold these exactly
+	assert.Equal(t, "12345678901234567890", toNumber("12345678901234567890", "count"))
+	assert.Equal(t, "0.1234567890123456", toNumber("0.1234567890123456", "count"))
+	assert.Equal(t, "02134", toNumber("02134", "address.zip"))
+	assert.Equal(t, []interface{}{int64(1), 2.5}, toNumbers([]string{"1", "2.5"}, "set"))
+	assert.Equal(t, []string{"02134"}, toNumbers([]string{"02134"}, "zip"))
+
+	Conf.Documents.Numbers.Default = NumbersString
+	assert.Equal(t, "123", toNumber("123", "count"))
+}
+
+func TestToIdCanonicalNumbers(t *testing.T) {
+	id, err := toId(map[string]events.DynamoDBAttributeValue{
+		"district": events.NewStringAttribute("abc"),
+		"version":  events.NewNumberAttribute("1.50"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "abc|1.5", id)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"reflect"
+)
+
+// toChanges returns the partial document that updates old to new. Objects are merged by
+// Elasticsearch, so they are compared attribute by attribute, while anything else is replaced
+// whole. Removed attributes are set to null, as leaving them out would keep their old value.
+func toChanges(old, new map[string]interface{}) map[string]interface{} {
+	changes := map[string]interface{}{}
+	for k, v := range new {
+		prev, ok := old[k]
+		if !ok {
+			changes[k] = v
+			continue
+		}
+		prevMap, prevIsMap := prev.(map[string]interface{})
+		vMap, vIsMap := v.(map[string]interface{})
+		if prevIsMap && vIsMap {
+			if nested := toChanges(prevMap, vMap); len(nested) > 0 {
+				changes[k] = nested
+			}
+			continue
+		}
+		if !reflect.DeepEqual(prev, v) {
+			changes[k] = v
+		}
+	}
+	for k := range old {
+		if _, ok := new[k]; !ok {
+			changes[k] = nil
+		}
+	}
+	return changes
+}
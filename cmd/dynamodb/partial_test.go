@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToChanges(t *testing.T) {
+	old := map[string]interface{}{
+		"same":    "a",
+		"changed": int64(1),
+		"removed": "b",
+		"list":    []interface{}{"x", "y"},
+		"nested": map[string]interface{}{
+			"same":    true,
+			"removed": "c",
+		},
+		"unchanged": map[string]interface{}{"a": "b"},
+	}
+	new := map[string]interface{}{
+		"same":    "a",
+		"changed": int64(2),
+		"added":   "d",
+		"list":    []interface{}{"x"},
+		"nested": map[string]interface{}{
+			"same":  true,
+			"added": "e",
+		},
+		"unchanged": map[string]interface{}{"a": "b"},
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"changed": int64(2),
+		"removed": nil,
+		"added":   "d",
+		"list":    []interface{}{"x"},
+		"nested": map[string]interface{}{
+			"removed": nil,
+			"added":   "e",
+		},
+	}, toChanges(old, new))
+	assert.Empty(t, toChanges(old, old))
+}
+
+func TestToDocPartialUpdates(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	Conf.Elasticsearch.PartialUpdates = true
+	record := events.DynamoDBEventRecord{
+		EventName: "MODIFY",
+		Change: events.DynamoDBStreamRecord{
+			Keys: map[string]events.DynamoDBAttributeValue{"id": events.NewStringAttribute("1")},
+			OldImage: map[string]events.DynamoDBAttributeValue{
+				"id":      events.NewStringAttribute("1"),
+				"name":    events.NewStringAttribute("old"),
+				"removed": events.NewBooleanAttribute(true),
+			},
+			NewImage: map[string]events.DynamoDBAttributeValue{
+				"id":   events.NewStringAttribute("1"),
+				"name": events.NewStringAttribute("new"),
+			},
+		},
+	}
+
+	doc, ok, err := toDoc(record)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, doc.Partial)
+	assert.Equal(t, map[string]interface{}{"name": "new", "removed": nil}, doc.Item)
+
+	// streams without old images can't be diffed
+	record.Change.OldImage = nil
+	doc, _, err = toDoc(record)
+	require.NoError(t, err)
+	assert.False(t, doc.Partial)
+	assert.Equal(t, map[string]interface{}{"id": "1", "name": "new"}, doc.Item)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PathPattern matches dotted attribute paths, such as "Workflow.workflowDefinition.stateMachine".
+// A "*" segment matches any single attribute name and a "**" segment matches any number of them.
+// Elements of lists share the path of the list.
+type PathPattern struct {
+	raw      string
+	segments []string
+}
+
+// ParsePathPattern parses a dotted path pattern. Problems are reported by Validate.
+func ParsePathPattern(raw string) PathPattern {
+	return PathPattern{raw: raw, segments: strings.Split(raw, ".")}
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (p *PathPattern) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	raw := ""
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	*p = ParsePathPattern(raw)
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler
+func (p PathPattern) MarshalYAML() (interface{}, error) {
+	return p.raw, nil
+}
+
+func (p PathPattern) String() string {
+	return p.raw
+}
+
+// Validate returns an error if the pattern can never match as intended
+func (p PathPattern) Validate() error {
+	if p.raw == "" {
+		return fmt.Errorf("pattern is empty")
+	}
+	for _, segment := range p.segments {
+		if segment == "" {
+			return fmt.Errorf("pattern %q has an empty segment", p.raw)
+		}
+		if segment != "*" && segment != "**" && strings.Contains(segment, "*") {
+			return fmt.Errorf("pattern %q may only use * and ** as whole segments", p.raw)
+		}
+	}
+	return nil
+}
+
+// Match returns true if the pattern matches the whole path
+func (p PathPattern) Match(path string) bool {
+	return matchSegments(p.segments, strings.Split(path, "."), false)
+}
+
+// MatchPrefix returns true if the pattern matches the path or any path nested below it
+func (p PathPattern) MatchPrefix(path string) bool {
+	return matchSegments(p.segments, strings.Split(path, "."), true)
+}
+
+// matchSegments matches pattern segments against path segments.
+// If prefix is true, running out of path before the pattern is a match.
+func matchSegments(pattern, path []string, prefix bool) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// try matching the rest of the pattern at every remaining position
+			for i := 0; i <= len(path); i++ {
+				if matchSegments(pattern[1:], path[i:], prefix) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 {
+			return prefix
+		}
+		if pattern[0] != "*" && pattern[0] != path[0] {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
+
+// matchAny returns true if any of the patterns match the path
+func matchAny(patterns []PathPattern, path string) bool {
+	for _, pattern := range patterns {
+		if pattern.Match(path) {
+			return true
+		}
+	}
+	return false
+}
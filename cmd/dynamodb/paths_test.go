@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+		prefix  bool
+	}{
+		{"Workflow.workflowDefinition.stateMachine", "Workflow.workflowDefinition.stateMachine", true, true},
+		{"Workflow.workflowDefinition.stateMachine", "Workflow.workflowDefinition", false, true},
+		{"Workflow.workflowDefinition.stateMachine", "Map.Workflow.workflowDefinition.stateMachine", false, false},
+		{"Workflow.*.stateMachine", "Workflow.workflowDefinition.stateMachine", true, true},
+		{"Workflow.*.stateMachine", "Workflow.a.b.stateMachine", false, false},
+		{"*", "Workflow", true, true},
+		{"*", "Workflow.workflowDefinition", false, false},
+		{"**.stateMachine", "stateMachine", true, true},
+		{"**.stateMachine", "Map.Workflow.workflowDefinition.stateMachine", true, true},
+		{"**.stateMachine", "Map.Workflow", false, true},
+		{"Map.**", "Map", true, true},
+		{"Map.**", "Map.Workflow.workflowDefinition", true, true},
+		{"Map.**", "List", false, false},
+		{"a.**.c.*", "a.b.b.c.d", true, true},
+		{"a.**.c.*", "a.b.b.c", false, true},
+	}
+
+	for _, test := range tests {
+		pattern := ParsePathPattern(test.pattern)
+		assert.Equal(t, test.match, pattern.Match(test.path), "%s matches %s", test.pattern, test.path)
+		assert.Equal(t, test.prefix, pattern.MatchPrefix(test.path), "%s prefix matches %s", test.pattern, test.path)
+	}
+}
+
+func TestToItemExcludes(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	Conf.Documents.Exclude = []PathPattern{ParsePathPattern("**.stateMachine")}
+
+	event := loadDynamoDBEvent(t)
+	doc, ok, err := toDoc(event.Records[1])
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	item := doc.Item.(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"workflowDefinition": map[string]interface{}{}}, item["Workflow"])
+	assert.Equal(t, map[string]interface{}{
+		"Age":      int64(35),
+		"Name":     "Joe",
+		"Workflow": map[string]interface{}{"workflowDefinition": map[string]interface{}{}},
+	}, item["Map"])
+}
+
+func TestToItemIncludes(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	Conf.Documents.Include = []PathPattern{
+		ParsePathPattern("String"),
+		ParsePathPattern("Map.*.workflowDefinition"),
+		ParsePathPattern("List"),
+		ParsePathPattern("Missing.attribute"),
+	}
+
+	event := loadDynamoDBEvent(t)
+	doc, ok, err := toDoc(event.Records[1])
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]interface{}{
+		"String": "Hello",
+		"List":   []interface{}{"Cookies", "Coffee", 3.14159},
+		"Map": map[string]interface{}{
+			"Workflow": map[string]interface{}{
+				"workflowDefinition": map[string]interface{}{
+					"stateMachine": map[string]interface{}{
+						"NumStates": int64(1),
+						"State1":    "state 1",
+					},
+				},
+			},
+		},
+	}, doc.Item)
+	// keys are always converted, even if they aren't included
+	assert.Equal(t, "binary|data", doc.ID)
+}
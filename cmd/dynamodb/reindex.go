@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/Clever/kayvee-go.v6/logger"
+
+	"github.com/Clever/ddb-to-es/es"
+)
+
+// reindex moves each write alias to a new version of its index, created from the template, and returns
+// the process exit code. Every index item documents are written to is reindexed if none are given.
+func reindex(db es.DB, aliases []string) int {
+	if !Conf.Elasticsearch.WriteAliases.Enabled {
+		fmt.Fprintln(os.Stderr, "reindex requires elasticsearch.writeAliases.enabled")
+		return 2
+	}
+	if len(aliases) == 0 {
+		aliases = Conf.itemIndices()
+	}
+	// checked before any alias is reindexed, rather than failing part way through
+	for _, alias := range aliases {
+		if Conf.Elasticsearch.VersionType == "" && !containsFold(Conf.Elasticsearch.SoftDeleteIndices, alias) {
+			fmt.Fprintf(os.Stderr, "reindexing %s requires elasticsearch.versionType or %s in softDeleteIndices, "+
+				"so that documents deleted while copying aren't restored\n", alias, alias)
+			return 2
+		}
+	}
+
+	reindexer, ok := db.(es.Reindexer)
+	if !ok {
+		fmt.Fprintln(os.Stderr, "indices can't be reindexed with this DB")
+		return 1
+	}
+	template, err := loadTemplate(Conf.Elasticsearch.Template.File)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	seen := map[string]bool{}
+	for _, alias := range aliases {
+		if seen[alias] {
+			continue
+		}
+		seen[alias] = true
+		result, err := reindexer.Reindex(alias, template)
+		if err != nil {
+			log.ErrorD("reindex-failed", logger.M{"alias": alias, "error": err.Error()})
+			return 1
+		}
+		fmt.Printf("%s: reindexed %s to %s, copying %d documents (%d already written)\n",
+			result.Alias, result.From, result.To, result.Created, result.Conflicts)
+	}
+	return 0
+}
+
+// containsFold returns true if values contains value, ignoring case as index names are lower-cased
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
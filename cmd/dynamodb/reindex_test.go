@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReindexRequiresVersioningOrSoftDeletes(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	Conf.Elasticsearch.WriteAliases.Enabled = true
+	Conf.Elasticsearch.Indices = []string{"users", "orgs"}
+	Conf.Elasticsearch.SoftDeleteIndices = []string{"Users"}
+
+	assert.Equal(t, 2, reindex(&MockDB{}, nil))
+	// MockDB can't reindex, so the check passing fails later
+	assert.Equal(t, 1, reindex(&MockDB{}, []string{"users"}))
+
+	Conf.Elasticsearch.VersionType = "external_gte"
+	assert.Equal(t, 1, reindex(&MockDB{}, nil))
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gopkg.in/Clever/kayvee-go.v6/logger"
+
+	"github.com/Clever/ddb-to-es/deadletter"
+)
+
+// replayBatchSize matches the batch size of the Lambda event source
+const replayBatchSize = 200
+
+// replay reads dead letter files and queues and processes their records again, returning the process
+// exit code. Records rejected again are sent to the dead letter sink, and fail the replay.
+func replay(sources []string) int {
+	if len(sources) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: replay <dead letter file, s3://bucket/key or sqs://host/account/queue>...")
+		return 2
+	}
+
+	exitCode := 0
+	for _, source := range sources {
+		var deadLettered int
+		var err error
+		if deadletter.IsQueue(source) {
+			var queue *deadletter.SQSQueue
+			if queue, err = deadletter.OpenQueue(source, Conf.Errors.DeadLetter.Endpoint); err == nil {
+				deadLettered, err = replayQueue(source, queue)
+			}
+		} else {
+			deadLettered, err = replayFile(source)
+		}
+		if err != nil {
+			log.ErrorD("replay-failed", logger.M{
+				"file":  source,
+				"error": err.Error(),
+			})
+			exitCode = 1
+		} else if deadLettered > 0 {
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
+
+// replayFile processes the records of a dead letter file in batches, returning the number of
+// records that were rejected and sent to the dead letter sink again
+func replayFile(file string) (int, error) {
+	f, err := deadletter.Open(file, Conf.Errors.DeadLetter.Endpoint)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	entries, err := deadletter.Decode(f)
+	if err != nil {
+		return 0, err
+	}
+
+	records, deadLettered, err := replayEntries(entries)
+	if err != nil {
+		return deadLettered, err
+	}
+	logReplay(file, records, deadLettered)
+	return deadLettered, nil
+}
+
+// replayQueue processes the records of a dead letter queue as they're received, deleting them once
+// replayed. It returns the number of records that were rejected and sent to the dead letter sink
+// again. Entries sent since the replay started, such as those of records rejected again, are left
+// in the queue.
+func replayQueue(queueURL string, queue *deadletter.SQSQueue) (int, error) {
+	started := time.Now()
+	records, deadLettered := 0, 0
+	for {
+		messages, err := queue.Receive()
+		if err != nil {
+			return deadLettered, err
+		}
+		entries := []deadletter.Entry{}
+		replayed := []deadletter.Message{}
+		for _, message := range messages {
+			// sent times are in milliseconds
+			if !message.Sent.Before(started.Truncate(time.Millisecond)) {
+				continue
+			}
+			entries = append(entries, message.Entry)
+			replayed = append(replayed, message)
+		}
+		if len(replayed) == 0 {
+			break
+		}
+
+		n, rejected, err := replayEntries(entries)
+		records += n
+		deadLettered += rejected
+		if err != nil {
+			return deadLettered, err
+		}
+		if err := queue.Delete(replayed); err != nil {
+			return deadLettered, err
+		}
+	}
+	logReplay(queueURL, records, deadLettered)
+	return deadLettered, nil
+}
+
+// replayEntries processes the records of dead letter entries in batches, returning the number of
+// records replayed and of those that were rejected and sent to the dead letter sink again
+func replayEntries(entries []deadletter.Entry) (int, int, error) {
+	// a record rejected by several indices has an entry per index, but only needs to be replayed once
+	records := []events.DynamoDBEventRecord{}
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		key := replayKey(entry.Record)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		records = append(records, entry.Record)
+	}
+
+	// records rejected again are counted as they're sent to the dead letter sink
+	sink := &replaySink{records: map[string]bool{}}
+	if DeadLetter != nil {
+		sink.Sink = DeadLetter
+		defer func(deadLetter deadletter.Sink) { DeadLetter = deadLetter }(DeadLetter)
+		DeadLetter = sink
+	}
+
+	for start := 0; start < len(records); start += replayBatchSize {
+		end := start + replayBatchSize
+		if end > len(records) {
+			end = len(records)
+		}
+		if _, err := processRecords(records[start:end], DBClient); err != nil && err != ErrAllRecordsSkipped {
+			return len(records), len(sink.records), err
+		}
+	}
+	return len(records), len(sink.records), nil
+}
+
+// logReplay logs the outcome of replaying the records of a dead letter file or queue
+func logReplay(source string, records, deadLettered int) {
+	if deadLettered > 0 {
+		log.ErrorD("replay-dead-lettered", logger.M{
+			"file":          source,
+			"records":       records,
+			"dead-lettered": deadLettered,
+		})
+		return
+	}
+	log.InfoD("replay-success", logger.M{
+		"file":    source,
+		"records": records,
+	})
+}
+
+// replayKey identifies the record of a dead letter entry
+func replayKey(record events.DynamoDBEventRecord) string {
+	return record.EventID + "|" + record.Change.SequenceNumber
+}
+
+// replaySink records which records are sent to the dead letter Sink it wraps
+type replaySink struct {
+	deadletter.Sink
+	records map[string]bool
+}
+
+// Send implements deadletter.Sink
+func (s *replaySink) Send(entries []deadletter.Entry) error {
+	if err := s.Sink.Send(entries); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		s.records[replayKey(entry.Record)] = true
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Clever/ddb-to-es/deadletter"
+	"github.com/Clever/ddb-to-es/es"
+)
+
+// writeDeadLetterFile writes a dead letter file of the fixture's records, the first rejected by two indices
+func writeDeadLetterFile(t *testing.T) string {
+	dir := t.TempDir()
+	sink, err := deadletter.New("file://"+dir, "")
+	require.NoError(t, err)
+	entries := []deadletter.Entry{}
+	for i, record := range loadDynamoDBEvent(t).Records {
+		indices := []string{"test-index"}
+		if i == 0 {
+			indices = append(indices, "other-index")
+		}
+		for _, index := range indices {
+			entries = append(entries, deadletter.Entry{
+				Record:    record,
+				Index:     index,
+				Status:    400,
+				ErrorType: "mapper_parsing_exception",
+				Time:      time.Date(2024, 7, 16, 15, 0, 0, 0, time.UTC),
+			})
+		}
+	}
+	require.NoError(t, sink.Send(entries))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	return files[0]
+}
+
+func TestReplayFile(t *testing.T) {
+	defer func(db es.DB, sink deadletter.Sink) { DBClient, DeadLetter = db, sink }(DBClient, DeadLetter)
+	file := writeDeadLetterFile(t)
+	sink := &MockSink{}
+	DeadLetter = sink
+
+	db := &recordingDB{}
+	DBClient = db
+	deadLettered, err := replayFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, 0, deadLettered)
+	// each record is replayed once
+	assert.Len(t, db.ids, len(loadDynamoDBEvent(t).Records))
+	assert.Empty(t, sink.entries)
+
+	// records rejected again are sent to the dead letter sink and counted
+	DBClient = &MockDB{failed: []int{0}}
+	deadLettered, err = replayFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deadLettered)
+	assert.Len(t, sink.entries, 1)
+	assert.Equal(t, sink, DeadLetter, "the dead letter sink is restored")
+
+	_, err = replayFile(filepath.Join(filepath.Dir(file), "missing.jsonl"))
+	assert.Error(t, err)
+}
+
+func TestReplayExitCodes(t *testing.T) {
+	defer func(db es.DB, sink deadletter.Sink) { DBClient, DeadLetter = db, sink }(DBClient, DeadLetter)
+	file := writeDeadLetterFile(t)
+	DeadLetter = &MockSink{}
+
+	assert.Equal(t, 2, replay(nil))
+
+	DBClient = &recordingDB{}
+	assert.Equal(t, 0, replay([]string{file}))
+
+	DBClient = &MockDB{failed: []int{0}}
+	assert.Equal(t, 1, replay([]string{file}))
+
+	DBClient = &recordingDB{}
+	assert.Equal(t, 1, replay([]string{file, "s3:///missing-bucket"}))
+}
+
+// mockQueue is an SQS queue whose messages are received in the order they're sent
+type mockQueue struct {
+	sqsiface.SQSAPI
+	messages []*sqs.Message
+	// received holds the receipt handles of the messages received, which are hidden from later receives
+	received map[string]bool
+	handles  int
+	// sent is the time messages are sent at, or now if zero
+	sent time.Time
+}
+
+func (m *mockQueue) SendMessageBatch(input *sqs.SendMessageBatchInput) (*sqs.SendMessageBatchOutput, error) {
+	sent := m.sent
+	if sent.IsZero() {
+		sent = time.Now()
+	}
+	for _, entry := range input.Entries {
+		handle := fmt.Sprintf("%d", m.handles)
+		m.handles++
+		m.messages = append(m.messages, &sqs.Message{
+			MessageId:     aws.String(handle),
+			ReceiptHandle: aws.String(handle),
+			Body:          entry.MessageBody,
+			Attributes: map[string]*string{
+				sqs.MessageSystemAttributeNameSentTimestamp: aws.String(strconv.FormatInt(sent.UnixNano()/int64(time.Millisecond), 10)),
+			},
+		})
+	}
+	return &sqs.SendMessageBatchOutput{}, nil
+}
+
+func (m *mockQueue) ReceiveMessage(input *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+	if m.received == nil {
+		m.received = map[string]bool{}
+	}
+	out := &sqs.ReceiveMessageOutput{}
+	for _, message := range m.messages {
+		handle := aws.StringValue(message.ReceiptHandle)
+		if !m.received[handle] && len(out.Messages) < int(aws.Int64Value(input.MaxNumberOfMessages)) {
+			m.received[handle] = true
+			out.Messages = append(out.Messages, message)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockQueue) DeleteMessageBatch(input *sqs.DeleteMessageBatchInput) (*sqs.DeleteMessageBatchOutput, error) {
+	for _, entry := range input.Entries {
+		for i, message := range m.messages {
+			if aws.StringValue(message.ReceiptHandle) == aws.StringValue(entry.ReceiptHandle) {
+				m.messages = append(m.messages[:i], m.messages[i+1:]...)
+				break
+			}
+		}
+	}
+	return &sqs.DeleteMessageBatchOutput{}, nil
+}
+
+func TestReplayQueue(t *testing.T) {
+	defer func(db es.DB, sink deadletter.Sink) { DBClient, DeadLetter = db, sink }(DBClient, DeadLetter)
+	queueURL := "https://sqs.us-west-2.amazonaws.com/123456789012/dead-letter"
+	client := &mockQueue{sent: time.Date(2024, 7, 16, 15, 0, 0, 0, time.UTC)}
+	entries := []deadletter.Entry{}
+	for i := 0; i < 12; i++ {
+		record := loadDynamoDBEvent(t).Records[0]
+		record.EventID = fmt.Sprintf("record-%d", i)
+		entries = append(entries, deadletter.Entry{Record: record, Index: "test-index", Status: 400})
+	}
+	require.NoError(t, deadletter.NewSQSSink(client, queueURL).Send(entries))
+	client.sent = time.Time{}
+
+	// the record rejected again is sent back to the queue, and left there
+	DeadLetter = deadletter.NewSQSSink(client, queueURL)
+	DBClient = &MockDB{failed: []int{0}}
+	deadLettered, err := replayQueue("sqs://sqs.us-west-2.amazonaws.com/123456789012/dead-letter", deadletter.NewSQSQueue(client, queueURL))
+	require.NoError(t, err)
+	assert.Equal(t, 2, deadLettered, "the first record of each batch received is rejected")
+	assert.Len(t, client.messages, 2)
+}
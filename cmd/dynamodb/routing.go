@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/Clever/ddb-to-es/es"
+)
+
+// IndexRoutingConfig routes documents to indices other than elasticsearch.indices, such as
+// the entity types of a single-table design told apart by their partition key prefix
+type IndexRoutingConfig struct {
+	// Rules are tried in order, and the first that matches a record picks its indices
+	Rules []IndexRule `yaml:"rules"`
+	// DropUnmatched skips records that no rule matches, instead of writing them to elasticsearch.indices
+	DropUnmatched bool `yaml:"dropUnmatched"`
+}
+
+// IndexRule routes the records it matches to indices
+type IndexRule struct {
+	AttributeMatch `yaml:",inline"`
+	// Indices the matching documents are written to. Supports the same date patterns as elasticsearch.indices.
+	Indices []string `yaml:"indices"`
+}
+
+// AttributeMatch matches records by the value of a key or top-level attribute
+type AttributeMatch struct {
+	// Attribute is the key or top-level attribute whose value is matched
+	Attribute string `yaml:"attribute"`
+	// Prefix matches values starting with it
+	Prefix string `yaml:"prefix"`
+	// Equals matches values equal to it. Numbers and booleans are matched as they are written.
+	Equals string `yaml:"equals"`
+}
+
+// validate returns every problem with the routing config
+func (c IndexRoutingConfig) validate() []error {
+	problems := []error{}
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+	for i, rule := range c.Rules {
+		problems = append(problems, rule.validate(fmt.Sprintf("elasticsearch.indexRouting.rules[%d]", i))...)
+		if len(rule.Indices) == 0 {
+			problem("elasticsearch.indexRouting.rules[%d].indices must list at least one index", i)
+		}
+		for j, index := range rule.Indices {
+			if strings.TrimSpace(index) == "" {
+				problem("elasticsearch.indexRouting.rules[%d].indices[%d] is empty", i, j)
+			} else if err := es.ValidateIndexName(index); err != nil {
+				problem("elasticsearch.indexRouting.rules[%d].indices[%d]: %s", i, j, err)
+			}
+		}
+	}
+	return problems
+}
+
+// validate returns every problem with the match, with field naming it in the config
+func (m AttributeMatch) validate(field string) []error {
+	problems := []error{}
+	if m.Attribute == "" {
+		problems = append(problems, fmt.Errorf("%s.attribute is required", field))
+	}
+	if (m.Prefix == "") == (m.Equals == "") {
+		problems = append(problems, fmt.Errorf("%s must set one of prefix or equals", field))
+	}
+	return problems
+}
+
+// matches returns true if the record's attribute matches
+func (m AttributeMatch) matches(record events.DynamoDBEventRecord) bool {
+	s, ok := attributeValue(record, m.Attribute)
+	if !ok {
+		return false
+	}
+	if m.Prefix != "" {
+		return strings.HasPrefix(s, m.Prefix)
+	}
+	return s == m.Equals
+}
+
+// attributeValue returns a string, number or boolean key or top-level attribute as it is written.
+// Attributes are looked up in the keys, then the new image, then the old image so that deletes find them too.
+func attributeValue(record events.DynamoDBEventRecord, name string) (string, bool) {
+	value, ok := record.Change.Keys[name]
+	if !ok {
+		value, ok = record.Change.NewImage[name]
+	}
+	if !ok {
+		value, ok = record.Change.OldImage[name]
+	}
+	if !ok {
+		return "", false
+	}
+
+	switch value.DataType() {
+	case events.DataTypeString:
+		return value.String(), true
+	case events.DataTypeNumber:
+		return value.Number(), true
+	case events.DataTypeBoolean:
+		return strconv.FormatBool(value.Boolean()), true
+	default:
+		return "", false
+	}
+}
+
+// toIndices returns the indices the record's document is routed to, or nil for elasticsearch.indices.
+// ok is false if the record matches no rule and unmatched records are dropped.
+func toIndices(record events.DynamoDBEventRecord) (indices []string, ok bool) {
+	routing := Conf.Elasticsearch.IndexRouting
+	for _, rule := range routing.Rules {
+		if rule.matches(record) {
+			return rule.Indices, true
+		}
+	}
+	return nil, !routing.DropUnmatched
+}
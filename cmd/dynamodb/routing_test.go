@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToIndices(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	Conf.Elasticsearch.IndexRouting = IndexRoutingConfig{
+		Rules: []IndexRule{
+			{AttributeMatch: AttributeMatch{Attribute: "pk", Prefix: "USER#"}, Indices: []string{"users"}},
+			{AttributeMatch: AttributeMatch{Attribute: "pk", Prefix: "ORG#"}, Indices: []string{"orgs", "orgs-{yyyy}"}},
+			{AttributeMatch: AttributeMatch{Attribute: "type", Equals: "invoice"}, Indices: []string{"invoices"}},
+		},
+	}
+	record := func(pk string, old map[string]events.DynamoDBAttributeValue) events.DynamoDBEventRecord {
+		return events.DynamoDBEventRecord{Change: events.DynamoDBStreamRecord{
+			Keys:     map[string]events.DynamoDBAttributeValue{"pk": events.NewStringAttribute(pk)},
+			OldImage: old,
+		}}
+	}
+
+	indices, ok := toIndices(record("USER#1", nil))
+	assert.True(t, ok)
+	assert.Equal(t, []string{"users"}, indices)
+
+	indices, ok = toIndices(record("ORG#1", nil))
+	assert.True(t, ok)
+	assert.Equal(t, []string{"orgs", "orgs-{yyyy}"}, indices)
+
+	// deletes are matched by their old image
+	indices, ok = toIndices(record("INV#1", map[string]events.DynamoDBAttributeValue{
+		"type": events.NewStringAttribute("invoice"),
+	}))
+	assert.True(t, ok)
+	assert.Equal(t, []string{"invoices"}, indices)
+
+	indices, ok = toIndices(record("OTHER#1", nil))
+	assert.True(t, ok)
+	assert.Nil(t, indices)
+
+	Conf.Elasticsearch.IndexRouting.DropUnmatched = true
+	_, ok = toIndices(record("OTHER#1", nil))
+	assert.False(t, ok)
+}
+
+func TestIndexRoutingValidate(t *testing.T) {
+	config := IndexRoutingConfig{Rules: []IndexRule{
+		{AttributeMatch: AttributeMatch{Attribute: "pk", Prefix: "USER#"}, Indices: []string{"users"}},
+		{AttributeMatch: AttributeMatch{Prefix: "USER#", Equals: "USER#1"}},
+		{AttributeMatch: AttributeMatch{Attribute: "pk", Equals: "x"}, Indices: []string{"users-{yyyy"}},
+	}}
+	assert.Len(t, config.validate(), 4)
+}
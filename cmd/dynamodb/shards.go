@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/Clever/ddb-to-es/es"
+)
+
+// RoutingConfig specifies the custom routing value that picks a document's shard, so that related
+// items, such as the items sharing a partition key, land on the same shard
+type RoutingConfig struct {
+	// Attribute is the key or top-level attribute holding the routing value. Default routing if empty.
+	Attribute string `yaml:"attribute"`
+	// Separator splits the attribute into components, such as "ORG#123#USER#4" split by "#".
+	// The whole attribute is used if empty.
+	Separator string `yaml:"separator"`
+	// Component is the zero-based index of the component used, when Separator is set
+	Component int `yaml:"component"`
+}
+
+// JoinConfig populates a join field, relating documents as parents and children
+type JoinConfig struct {
+	// Field is the name of the join field. Disabled if empty.
+	Field string `yaml:"field"`
+	// Relations are tried in order, and the first that matches a record sets its relation
+	Relations []JoinRelation `yaml:"relations"`
+}
+
+// JoinRelation sets the relation of the records it matches
+type JoinRelation struct {
+	AttributeMatch `yaml:",inline"`
+	// Name of the relation
+	Name string `yaml:"name"`
+	// Parent lists the attributes whose values, joined by documents.id.separator, make up the parent's
+	// document ID. Unset for parents.
+	Parent []string `yaml:"parent"`
+}
+
+// validate returns every problem with the routing config
+func (c RoutingConfig) validate() []error {
+	problems := []error{}
+	if c.Component < 0 {
+		problems = append(problems, fmt.Errorf("documents.routing.component must not be negative"))
+	}
+	if c.Component > 0 && c.Separator == "" {
+		problems = append(problems, fmt.Errorf("documents.routing.separator is required to pick a component"))
+	}
+	return problems
+}
+
+// validate returns every problem with the join config
+func (c JoinConfig) validate(routing RoutingConfig) []error {
+	problems := []error{}
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+	if c.Field == "" {
+		if len(c.Relations) > 0 {
+			problem("documents.join.field is required for relations")
+		}
+		return problems
+	}
+	if _, ok := es.ESReservedFields[c.Field]; ok {
+		problem("documents.join.field %q is reserved", c.Field)
+	}
+	// children must be on the same shard as their parent
+	if routing.Attribute == "" {
+		problem("documents.routing.attribute is required for joins")
+	}
+	for i, relation := range c.Relations {
+		problems = append(problems, relation.validate(fmt.Sprintf("documents.join.relations[%d]", i))...)
+		if relation.Name == "" {
+			problem("documents.join.relations[%d].name is required", i)
+		}
+	}
+	return problems
+}
+
+// toRouting returns the routing value of the record's document, or "" for default routing
+func toRouting(record events.DynamoDBEventRecord) (string, error) {
+	config := Conf.Documents.Routing
+	if config.Attribute == "" {
+		return "", nil
+	}
+	value, ok := attributeValue(record, config.Attribute)
+	if !ok {
+		// writing with default routing would put the document on the wrong shard
+		return "", fmt.Errorf("missing routing attribute %s", config.Attribute)
+	}
+	if config.Separator == "" {
+		return value, nil
+	}
+	components := strings.Split(value, config.Separator)
+	if config.Component >= len(components) {
+		return "", fmt.Errorf("routing attribute %s has no component %d", config.Attribute, config.Component)
+	}
+	return components[config.Component], nil
+}
+
+// toJoin returns the join field value of the record's document, or nil if it matches no relation
+func toJoin(record events.DynamoDBEventRecord) (map[string]interface{}, error) {
+	config := Conf.Documents.Join
+	if config.Field == "" {
+		return nil, nil
+	}
+	for _, relation := range config.Relations {
+		if !relation.matches(record) {
+			continue
+		}
+		join := map[string]interface{}{"name": relation.Name}
+		if len(relation.Parent) == 0 {
+			return join, nil
+		}
+		values := []string{}
+		for _, attribute := range relation.Parent {
+			value, ok := attributeValue(record, attribute)
+			if !ok {
+				return nil, fmt.Errorf("missing parent attribute %s", attribute)
+			}
+			values = append(values, value)
+		}
+		join["parent"] = strings.Join(values, Conf.Documents.ID.Separator)
+		return join, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToRouting(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	record := events.DynamoDBEventRecord{Change: events.DynamoDBStreamRecord{
+		Keys: map[string]events.DynamoDBAttributeValue{
+			"pk": events.NewStringAttribute("ORG#123"),
+			"sk": events.NewStringAttribute("USER#4"),
+		},
+	}}
+
+	routing, err := toRouting(record)
+	assert.NoError(t, err)
+	assert.Equal(t, "", routing)
+
+	Conf.Documents.Routing = RoutingConfig{Attribute: "pk"}
+	routing, err = toRouting(record)
+	assert.NoError(t, err)
+	assert.Equal(t, "ORG#123", routing)
+
+	Conf.Documents.Routing = RoutingConfig{Attribute: "pk", Separator: "#", Component: 1}
+	routing, err = toRouting(record)
+	assert.NoError(t, err)
+	assert.Equal(t, "123", routing)
+
+	Conf.Documents.Routing = RoutingConfig{Attribute: "pk", Separator: "#", Component: 2}
+	_, err = toRouting(record)
+	assert.Error(t, err)
+
+	Conf.Documents.Routing = RoutingConfig{Attribute: "orgId"}
+	_, err = toRouting(record)
+	assert.Error(t, err)
+}
+
+func TestToDocJoin(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	Conf.Documents.Routing = RoutingConfig{Attribute: "pk"}
+	Conf.Documents.Join = JoinConfig{
+		Field: "relation",
+		Relations: []JoinRelation{
+			{AttributeMatch: AttributeMatch{Attribute: "sk", Prefix: "ORG#"}, Name: "org"},
+			{AttributeMatch: AttributeMatch{Attribute: "sk", Prefix: "USER#"}, Name: "user", Parent: []string{"pk", "pk"}},
+		},
+	}
+	require.Empty(t, Conf.Documents.Join.validate(Conf.Documents.Routing))
+	record := func(eventName, sk string) events.DynamoDBEventRecord {
+		keys := map[string]events.DynamoDBAttributeValue{
+			"pk": events.NewStringAttribute("ORG#123"),
+			"sk": events.NewStringAttribute(sk),
+		}
+		change := events.DynamoDBStreamRecord{Keys: keys}
+		if eventName == "REMOVE" {
+			change.OldImage = keys
+		} else {
+			change.NewImage = keys
+		}
+		return events.DynamoDBEventRecord{EventName: eventName, Change: change}
+	}
+
+	doc, ok, err := toDoc(record("INSERT", "ORG#123"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "ORG#123", doc.Routing)
+	assert.Equal(t, map[string]interface{}{"name": "org"}, doc.Item.(map[string]interface{})["relation"])
+
+	doc, _, err = toDoc(record("MODIFY", "USER#4"))
+	require.NoError(t, err)
+	assert.Equal(t, "ORG#123", doc.Routing)
+	assert.Equal(t, map[string]interface{}{"name": "user", "parent": "ORG#123|ORG#123"},
+		doc.Item.(map[string]interface{})["relation"])
+
+	// deletes are routed to the same shard
+	doc, _, err = toDoc(record("REMOVE", "USER#4"))
+	require.NoError(t, err)
+	assert.Equal(t, "ORG#123", doc.Routing)
+}
+
+func TestJoinValidate(t *testing.T) {
+	join := JoinConfig{Field: "_routing", Relations: []JoinRelation{{}}}
+	// reserved field, missing routing, attribute, prefix or equals, and name
+	assert.Len(t, join.validate(RoutingConfig{}), 5)
+	assert.Len(t, RoutingConfig{Component: 1}.validate(), 1)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/Clever/kayvee-go.v6/logger"
+
+	"github.com/Clever/ddb-to-es/es"
+)
+
+// TemplateConfig declares the settings and mappings of the indices written to, so that they don't
+// rely on dynamic mapping
+type TemplateConfig struct {
+	// File is a JSON file with the settings and mappings. Defaults to the template.json bundled with the binary.
+	File string `yaml:"file"`
+	// Bootstrap creates the missing indices and index templates on startup, and logs where the
+	// mappings of those that exist drift from the template
+	Bootstrap bool `yaml:"bootstrap"`
+}
+
+// loadTemplate reads the template from the file, or the bundled template.json if it's empty
+func loadTemplate(file string) (es.Template, error) {
+	data, err := Asset("template.json")
+	if file != "" {
+		data, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return es.Template{}, fmt.Errorf("could not read template: %s", err)
+	}
+	template := es.Template{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&template); err != nil {
+		return es.Template{}, fmt.Errorf("could not parse template: %s", err)
+	}
+	return template, nil
+}
+
+// bootstrapConfig returns the indices bootstrapped from the template: every index item documents
+// are written to. The history index holds changes rather than items, so is left to dynamic mapping.
+func bootstrapConfig() (*es.BootstrapConfig, error) {
+	template, err := loadTemplate(Conf.Elasticsearch.Template.File)
+	if err != nil {
+		return nil, err
+	}
+	return &es.BootstrapConfig{Template: template, Indices: Conf.itemIndices()}, nil
+}
+
+// itemIndices returns every index item documents are written to, as configured
+func (c Config) itemIndices() []string {
+	indices := append([]string{}, c.Elasticsearch.Indices...)
+	for _, rule := range c.Elasticsearch.IndexRouting.Rules {
+		indices = append(indices, rule.Indices...)
+	}
+	if c.Documents.TTL.Policy == TTLPolicyArchive {
+		indices = append(indices, c.Documents.TTL.ArchiveIndex)
+	}
+	return indices
+}
+
+// bootstrap creates the missing indices and index templates, printing where the others drift from
+// the template, and returns the process exit code
+func bootstrap(db es.DB) int {
+	bootstrapper, ok := db.(es.Bootstrapper)
+	if !ok {
+		fmt.Fprintln(os.Stderr, "indices can't be bootstrapped with this DB")
+		return 1
+	}
+	config, err := bootstrapConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	drift, err := bootstrapper.Bootstrap(*config)
+	if err != nil {
+		log.ErrorD("bootstrap-failed", logger.M{"error": err.Error()})
+		return 1
+	}
+	for _, d := range drift {
+		fmt.Println(d)
+	}
+	if len(drift) > 0 {
+		fmt.Fprintf(os.Stderr, "%d mapping parameters drift from the template\n", len(drift))
+		return 1
+	}
+	fmt.Println("indices match the template")
+	return 0
+}
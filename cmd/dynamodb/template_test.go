@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTemplate(t *testing.T) {
+	template, err := loadTemplate("")
+	require.NoError(t, err)
+	assert.Contains(t, template.Mappings, "dynamic_templates")
+
+	file := filepath.Join(t.TempDir(), "template.json")
+	require.NoError(t, ioutil.WriteFile(file, []byte(`{"mappings":{"dynamic":"strict"}}`), 0644))
+	template, err = loadTemplate(file)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"dynamic": "strict"}, template.Mappings)
+
+	require.NoError(t, ioutil.WriteFile(file, []byte(`{"mapping":{}}`), 0644))
+	_, err = loadTemplate(file)
+	assert.EqualError(t, err, `could not parse template: json: unknown field "mapping"`)
+}
+
+func TestBootstrapConfig(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	Conf.Elasticsearch.Indices = []string{"users"}
+	Conf.Elasticsearch.IndexRouting.Rules = []IndexRule{{Indices: []string{"orgs-{yyyy}"}}}
+	Conf.Elasticsearch.HistoryIndex = "history"
+	Conf.Documents.TTL = TTLConfig{Policy: TTLPolicyArchive, ArchiveIndex: "archive"}
+
+	config, err := bootstrapConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users", "orgs-{yyyy}", "archive"}, config.Indices)
+}
+
+func TestWriteAliasesRejectDatePatterns(t *testing.T) {
+	config := defaultConfig()
+	config.Elasticsearch.URL = "http://localhost:9200"
+	config.Elasticsearch.Indices = []string{"users", "events-{yyyy.MM}"}
+	config.Elasticsearch.WriteAliases.Enabled = true
+	config.Documents.Timestamp = TimestampConfig{Source: TimestampSourceAttribute, Attribute: "createdAt", Format: TimestampFormatRFC3339}
+	assert.Equal(t, []error{
+		errors.New("elasticsearch.writeAliases can't be used with index events-{yyyy.MM}, which has date patterns"),
+	}, config.Validate())
+}
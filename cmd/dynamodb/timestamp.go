@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/Clever/ddb-to-es/es"
+)
+
+// Sources of the timestamp that resolves date patterns in index names
+const (
+	// TimestampSourceCreationTime uses the stream record's creation time
+	TimestampSourceCreationTime = "creation-time"
+	// TimestampSourceAttribute uses an attribute of the item, so that deletes resolve to the
+	// same index as the item was written to
+	TimestampSourceAttribute = "attribute"
+)
+
+// Formats of timestamp attributes
+const (
+	TimestampFormatRFC3339      = "rfc3339"
+	TimestampFormatEpochSeconds = "epoch-seconds"
+	TimestampFormatEpochMillis  = "epoch-millis"
+)
+
+// TimestampConfig specifies the timestamp that resolves date patterns in index names
+type TimestampConfig struct {
+	// Source is creation-time or attribute
+	Source string `yaml:"source"`
+	// Attribute is the top-level attribute holding the timestamp, when Source is attribute
+	Attribute string `yaml:"attribute"`
+	// Format is rfc3339 for string attributes, or epoch-seconds or epoch-millis for number attributes
+	Format string `yaml:"format"`
+}
+
+// validate returns every problem with the timestamp config
+func (c TimestampConfig) validate() []error {
+	problems := []error{}
+	switch c.Source {
+	case TimestampSourceCreationTime:
+	case TimestampSourceAttribute:
+		if c.Attribute == "" {
+			problems = append(problems, fmt.Errorf("documents.timestamp.attribute is required when the source is %s", c.Source))
+		}
+		switch c.Format {
+		case TimestampFormatRFC3339, TimestampFormatEpochSeconds, TimestampFormatEpochMillis:
+		default:
+			problems = append(problems, fmt.Errorf("documents.timestamp.format %q must be one of %s, %s, %s",
+				c.Format, TimestampFormatRFC3339, TimestampFormatEpochSeconds, TimestampFormatEpochMillis))
+		}
+	default:
+		problems = append(problems, fmt.Errorf("documents.timestamp.source %q must be %s or %s",
+			c.Source, TimestampSourceCreationTime, TimestampSourceAttribute))
+	}
+	return problems
+}
+
+// toTimestamp returns the timestamp of the document written for a record.
+// When it comes from an attribute, the old image is used for deletes. Records without the attribute
+// fail, whatever their operation, as changes and deletes couldn't find the index the document was
+// written to.
+func toTimestamp(record events.DynamoDBEventRecord) (time.Time, error) {
+	config := Conf.Documents.Timestamp
+	if config.Source != TimestampSourceAttribute {
+		return record.Change.ApproximateCreationDateTime.Time, nil
+	}
+
+	value, ok := record.Change.NewImage[config.Attribute]
+	if !ok {
+		value, ok = record.Change.OldImage[config.Attribute]
+	}
+	if !ok || value.IsNull() {
+		return time.Time{}, fmt.Errorf("record %s has no timestamp attribute %s to find the document's index by", record.EventID, config.Attribute)
+	}
+	return parseTimestamp(value)
+}
+
+// toMovedIndices returns the index patterns that a change to the timestamp attribute moves a
+// document out of, along with the timestamp that resolves the indices it was in
+func toMovedIndices(record events.DynamoDBEventRecord, doc es.Doc) ([]string, time.Time, error) {
+	config := Conf.Documents.Timestamp
+	if config.Source != TimestampSourceAttribute || record.EventName != string(events.DynamoDBOperationTypeModify) {
+		return nil, time.Time{}, nil
+	}
+	value, ok := record.Change.OldImage[config.Attribute]
+	if !ok || value.IsNull() {
+		return nil, time.Time{}, nil
+	}
+	previous, err := parseTimestamp(value)
+	if err != nil || previous.Equal(doc.Timestamp) {
+		return nil, time.Time{}, err
+	}
+
+	patterns := doc.Indices
+	if len(patterns) == 0 {
+		patterns = Conf.Elasticsearch.Indices
+	}
+	moved := []string{}
+	for _, pattern := range patterns {
+		from, err := es.IndexName(pattern, previous)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		to, err := es.IndexName(pattern, doc.Timestamp)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		if from != to {
+			moved = append(moved, pattern)
+		}
+	}
+	if len(moved) == 0 {
+		return nil, time.Time{}, nil
+	}
+	return moved, previous, nil
+}
+
+// toMovedDoc returns the delete of a document from the indices a change to its timestamp attribute
+// moves it out of. ok is false if it stays in the same indices.
+func toMovedDoc(record events.DynamoDBEventRecord, doc es.Doc) (moved es.Doc, ok bool, err error) {
+	indices, previous, err := toMovedIndices(record, doc)
+	if err != nil || len(indices) == 0 {
+		return es.Doc{}, false, err
+	}
+	moved = doc
+	moved.Op = es.OpTypeDelete
+	moved.Item = toImage(record.Change.OldImage)
+	moved.Timestamp = previous
+	moved.Indices = indices
+	moved.Partial = false
+	moved.DeletedAt = record.Change.ApproximateCreationDateTime.Time
+	return moved, true, nil
+}
+
+// parseTimestamp parses a timestamp attribute in the configured format
+func parseTimestamp(value events.DynamoDBAttributeValue) (time.Time, error) {
+	config := Conf.Documents.Timestamp
+	switch config.Format {
+	case TimestampFormatRFC3339:
+		if value.DataType() != events.DataTypeString {
+			break
+		}
+		t, err := time.Parse(time.RFC3339Nano, value.String())
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp attribute %s: %s", config.Attribute, err)
+		}
+		return t, nil
+	case TimestampFormatEpochSeconds, TimestampFormatEpochMillis:
+		if value.DataType() != events.DataTypeNumber {
+			break
+		}
+		epoch, err := strconv.ParseFloat(value.Number(), 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp attribute %s: %s", config.Attribute, err)
+		}
+		if config.Format == TimestampFormatEpochMillis {
+			epoch /= 1000
+		}
+		seconds := int64(epoch)
+		return time.Unix(seconds, int64((epoch-float64(seconds))*1e9)), nil
+	}
+	return time.Time{}, fmt.Errorf("timestamp attribute %s is not in %s format", config.Attribute, config.Format)
+}
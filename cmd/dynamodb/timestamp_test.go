@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Clever/ddb-to-es/es"
+)
+
+func TestToTimestamp(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	created := time.Unix(1480642020, 0)
+	record := func(image map[string]events.DynamoDBAttributeValue, old bool) events.DynamoDBEventRecord {
+		change := events.DynamoDBStreamRecord{ApproximateCreationDateTime: events.SecondsEpochTime{Time: created}}
+		if old {
+			change.OldImage = image
+			return events.DynamoDBEventRecord{EventName: string(events.DynamoDBOperationTypeRemove), Change: change}
+		}
+		change.NewImage = image
+		return events.DynamoDBEventRecord{EventName: string(events.DynamoDBOperationTypeInsert), Change: change}
+	}
+
+	Conf.Documents.Timestamp = TimestampConfig{Source: TimestampSourceCreationTime}
+	ts, err := toTimestamp(record(nil, false))
+	assert.NoError(t, err)
+	assert.Equal(t, created, ts)
+
+	Conf.Documents.Timestamp = TimestampConfig{Source: TimestampSourceAttribute, Attribute: "createdAt", Format: TimestampFormatRFC3339}
+	ts, err = toTimestamp(record(map[string]events.DynamoDBAttributeValue{
+		"createdAt": events.NewStringAttribute("2024-07-16T15:00:00-07:00"),
+	}, false))
+	assert.NoError(t, err)
+	assert.True(t, time.Date(2024, 7, 16, 22, 0, 0, 0, time.UTC).Equal(ts))
+
+	// deletes only have an old image
+	Conf.Documents.Timestamp.Format = TimestampFormatEpochMillis
+	ts, err = toTimestamp(record(map[string]events.DynamoDBAttributeValue{
+		"createdAt": events.NewNumberAttribute("1721167200500"),
+	}, true))
+	assert.NoError(t, err)
+	assert.True(t, time.Date(2024, 7, 16, 22, 0, 0, 5e8, time.UTC).Equal(ts))
+
+	// records without the attribute fail whatever their operation, as a delete without it, such as
+	// from a stream without old images, couldn't find the index the document was written to
+	_, err = toTimestamp(record(map[string]events.DynamoDBAttributeValue{}, false))
+	assert.Error(t, err)
+	_, err = toTimestamp(record(nil, true))
+	assert.Error(t, err)
+	modify := record(nil, false)
+	modify.EventName = string(events.DynamoDBOperationTypeModify)
+	_, err = toTimestamp(modify)
+	assert.Error(t, err)
+
+	_, err = toTimestamp(record(map[string]events.DynamoDBAttributeValue{
+		"createdAt": events.NewStringAttribute("yesterday"),
+	}, false))
+	assert.Error(t, err)
+}
+
+func TestToDocsDeletesMovedDocuments(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	Conf.Elasticsearch.Indices = []string{"events-{yyyy.MM}", "events"}
+	Conf.Elasticsearch.PartialUpdates = true
+	Conf.Documents.Timestamp = TimestampConfig{Source: TimestampSourceAttribute, Attribute: "createdAt", Format: TimestampFormatRFC3339}
+	modify := func(old, new string) events.DynamoDBEventRecord {
+		return events.DynamoDBEventRecord{
+			EventID:   "1",
+			EventName: string(events.DynamoDBOperationTypeModify),
+			Change: events.DynamoDBStreamRecord{
+				Keys:     map[string]events.DynamoDBAttributeValue{"id": events.NewStringAttribute("a")},
+				OldImage: map[string]events.DynamoDBAttributeValue{"createdAt": events.NewStringAttribute(old), "n": events.NewNumberAttribute("1")},
+				NewImage: map[string]events.DynamoDBAttributeValue{"createdAt": events.NewStringAttribute(new), "n": events.NewNumberAttribute("2")},
+			},
+		}
+	}
+
+	// a change within the same month stays in the same indices
+	docs, err := toDocs(modify("2024-07-01T00:00:00Z", "2024-07-16T00:00:00Z"))
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.True(t, docs[0].Partial)
+
+	// a change to another month deletes the document from the index of the previous month only,
+	// and writes it whole to the new one
+	docs, err = toDocs(modify("2024-06-30T00:00:00Z", "2024-07-16T00:00:00Z"))
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.Equal(t, es.OpTypeDelete, docs[0].Op)
+	assert.Equal(t, []string{"events-{yyyy.MM}"}, docs[0].Indices)
+	assert.True(t, time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC).Equal(docs[0].Timestamp))
+	assert.Equal(t, es.OpTypeUpdate, docs[1].Op)
+	assert.Empty(t, docs[1].Indices)
+	assert.False(t, docs[1].Partial)
+	assert.Equal(t, map[string]interface{}{"createdAt": "2024-07-16T00:00:00Z", "n": int64(2)}, docs[1].Item)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/Clever/ddb-to-es/es"
+)
+
+// Policies for deletes made by DynamoDB's TTL expiry
+const (
+	// TTLPolicyDelete handles expired items like any other delete
+	TTLPolicyDelete = "delete"
+	// TTLPolicyKeep leaves their documents untouched, so search retains them
+	TTLPolicyKeep = "keep"
+	// TTLPolicyTombstone marks their documents as deleted in every index, as soft deletes do
+	TTLPolicyTombstone = "tombstone"
+	// TTLPolicyArchive deletes their documents, and keeps a soft deleted copy in the archive index
+	TTLPolicyArchive = "archive"
+)
+
+// TTLConfig specifies how deletes made by DynamoDB's TTL expiry are handled
+type TTLConfig struct {
+	// Policy is delete, keep, tombstone or archive
+	Policy string `yaml:"policy"`
+	// ArchiveIndex receives the documents of expired items with the archive policy.
+	// Supports the same date patterns as elasticsearch.indices.
+	ArchiveIndex string `yaml:"archiveIndex"`
+}
+
+// validate returns every problem with the TTL config
+func (c TTLConfig) validate() []error {
+	problems := []error{}
+	switch c.Policy {
+	case TTLPolicyDelete, TTLPolicyKeep, TTLPolicyTombstone:
+	case TTLPolicyArchive:
+		if c.ArchiveIndex == "" {
+			problems = append(problems, fmt.Errorf("documents.ttl.archiveIndex is required when the policy is %s", c.Policy))
+		}
+	default:
+		problems = append(problems, fmt.Errorf("documents.ttl.policy %q must be one of %s, %s, %s, %s",
+			c.Policy, TTLPolicyDelete, TTLPolicyKeep, TTLPolicyTombstone, TTLPolicyArchive))
+	}
+	if err := es.ValidateIndexName(c.ArchiveIndex); err != nil {
+		problems = append(problems, fmt.Errorf("documents.ttl.archiveIndex: %s", err))
+	}
+	return problems
+}
+
+// isExpired returns true if the record is a delete made by DynamoDB's TTL expiry.
+// See https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/time-to-live-ttl-streams.html
+func isExpired(record events.DynamoDBEventRecord) bool {
+	identity := record.UserIdentity
+	return events.DynamoDBOperationType(record.EventName) == events.DynamoDBOperationTypeRemove &&
+		identity != nil && identity.Type == "Service" && identity.PrincipalID == "dynamodb.amazonaws.com"
+}
+
+// toArchiveDoc returns the soft deleted copy of an expired item's document, written to the archive index
+func toArchiveDoc(doc es.Doc) es.Doc {
+	doc.Indices = []string{Conf.Documents.TTL.ArchiveIndex}
+	doc.SoftDelete = true
+	return doc
+}
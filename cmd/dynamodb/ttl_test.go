@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToDocDeletes(t *testing.T) {
+	deleted := time.Unix(1480642020, 0)
+	record := events.DynamoDBEventRecord{
+		EventName: "REMOVE",
+		Change: events.DynamoDBStreamRecord{
+			ApproximateCreationDateTime: events.SecondsEpochTime{Time: deleted},
+			Keys:                        map[string]events.DynamoDBAttributeValue{"id": events.NewStringAttribute("1")},
+			OldImage: map[string]events.DynamoDBAttributeValue{
+				"id":   events.NewStringAttribute("1"),
+				"name": events.NewStringAttribute("last"),
+			},
+		},
+	}
+
+	doc, ok, err := toDoc(record)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"id": "1", "name": "last"}, doc.Item)
+	assert.Equal(t, deleted, doc.DeletedAt)
+	assert.False(t, doc.Expired)
+
+	record.UserIdentity = &events.DynamoDBUserIdentity{Type: "Service", PrincipalID: "dynamodb.amazonaws.com"}
+	doc, _, err = toDoc(record)
+	require.NoError(t, err)
+	assert.True(t, doc.Expired)
+
+	// only deletes expire
+	record.EventName = "MODIFY"
+	assert.False(t, isExpired(record))
+}
+
+func TestToDocsTTLPolicies(t *testing.T) {
+	defer func(conf Config) { Conf = conf }(Conf)
+	record := events.DynamoDBEventRecord{
+		EventName:    "REMOVE",
+		UserIdentity: &events.DynamoDBUserIdentity{Type: "Service", PrincipalID: "dynamodb.amazonaws.com"},
+		Change: events.DynamoDBStreamRecord{
+			Keys:     map[string]events.DynamoDBAttributeValue{"id": events.NewStringAttribute("1")},
+			OldImage: map[string]events.DynamoDBAttributeValue{"id": events.NewStringAttribute("1")},
+		},
+	}
+	deleted := record
+	deleted.UserIdentity = nil
+
+	Conf.Documents.TTL = TTLConfig{Policy: TTLPolicyDelete}
+	docs, err := toDocs(record)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.False(t, docs[0].SoftDelete)
+
+	Conf.Documents.TTL = TTLConfig{Policy: TTLPolicyKeep}
+	docs, err = toDocs(record)
+	require.NoError(t, err)
+	assert.Empty(t, docs)
+	docs, err = toDocs(deleted)
+	require.NoError(t, err)
+	assert.Len(t, docs, 1)
+
+	Conf.Documents.TTL = TTLConfig{Policy: TTLPolicyTombstone}
+	docs, err = toDocs(record)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.True(t, docs[0].SoftDelete)
+
+	Conf.Documents.TTL = TTLConfig{Policy: TTLPolicyArchive, ArchiveIndex: "archive-{yyyy}"}
+	docs, err = toDocs(record)
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.False(t, docs[0].SoftDelete)
+	assert.Nil(t, docs[0].Indices)
+	assert.True(t, docs[1].SoftDelete)
+	assert.Equal(t, []string{"archive-{yyyy}"}, docs[1].Indices)
+	assert.Equal(t, map[string]interface{}{"id": "1"}, docs[1].Item)
+	docs, err = toDocs(deleted)
+	require.NoError(t, err)
+	assert.Len(t, docs, 1)
+
+	assert.Len(t, TTLConfig{Policy: TTLPolicyArchive}.validate(), 1)
+	assert.Len(t, TTLConfig{Policy: "expire"}.validate(), 1)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Sources documents can be versioned by when external versioning is enabled.
+// Stream sequence numbers aren't one: they're longer than a signed 64-bit integer holds.
+const (
+	// VersionSourceCreationTime versions documents by the stream record's creation time in milliseconds,
+	// which DynamoDB only records to the second. Records created in the same second get the same
+	// version, so it requires external_gte, and an older one redelivered after a newer one still wins.
+	VersionSourceCreationTime = "creation-time"
+)
+
+// toVersion derives the version of the document written for a record
+func toVersion(record events.DynamoDBEventRecord) (int64, error) {
+	switch Conf.Documents.VersionSource {
+	case "":
+		return 0, nil
+	case VersionSourceCreationTime:
+		created := record.Change.ApproximateCreationDateTime
+		if created.IsZero() {
+			return 0, fmt.Errorf("record %s has no creation time to use as a version", record.EventID)
+		}
+		return created.UnixNano() / 1e6, nil
+	default:
+		return 0, fmt.Errorf("unknown version source %s", Conf.Documents.VersionSource)
+	}
+}
@@ -0,0 +1,234 @@
+package deadletter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+
+	"github.com/Clever/ddb-to-es/es"
+)
+
+// awsConfig uses the standard AWS credential chain, optionally against a compatible endpoint
+func awsConfig(endpoint string) *aws.Config {
+	config := aws.NewConfig()
+	if endpoint != "" {
+		config = config.WithEndpoint(endpoint).WithS3ForcePathStyle(true)
+	}
+	return config
+}
+
+func newS3Client(endpoint string) s3iface.S3API {
+	return s3.New(session.Must(session.NewSession()), awsConfig(endpoint))
+}
+
+func newSQSClient(endpoint string) sqsiface.SQSAPI {
+	return sqs.New(session.Must(session.NewSession()), awsConfig(endpoint))
+}
+
+// S3Sink writes each batch of entries as a JSON lines object to an S3 bucket
+type S3Sink struct {
+	client s3iface.S3API
+	bucket string
+	prefix string
+}
+
+// NewS3Sink creates an S3Sink writing objects under prefix in bucket
+func NewS3Sink(client s3iface.S3API, bucket, prefix string) *S3Sink {
+	return &S3Sink{client: client, bucket: bucket, prefix: prefix}
+}
+
+// Send implements Sink
+func (s *S3Sink) Send(entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+	if err := encode(buf, entries); err != nil {
+		return err
+	}
+	_, err := s.client.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(path.Join(s.prefix, objectName(time.Now()))),
+		Body:        bytes.NewReader(buf.Bytes()),
+		ContentType: aws.String("application/x-ndjson"),
+	})
+	return err
+}
+
+// openS3Object opens an object written by an S3Sink
+func openS3Object(client s3iface.S3API, bucket, key string) (io.ReadCloser, error) {
+	out, err := client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+// SQSSink sends each entry as a message to an SQS queue
+type SQSSink struct {
+	client   sqsiface.SQSAPI
+	queueURL string
+}
+
+// NewSQSSink creates an SQSSink sending to the queue at queueURL
+func NewSQSSink(client sqsiface.SQSAPI, queueURL string) *SQSSink {
+	return &SQSSink{client: client, queueURL: queueURL}
+}
+
+// sqsBatchSize is the maximum number of messages in a SendMessageBatch request
+const sqsBatchSize = 10
+
+// sqsMaxBytes is the maximum size of a message, and of all the messages of a SendMessageBatch request
+const sqsMaxBytes = 256 * 1024
+
+// Send implements Sink. Entries are sent in batches of as many as fit in a request.
+func (s *SQSSink) Send(entries []Entry) error {
+	bodies := []string{}
+	for _, entry := range entries {
+		body, err := sqsBody(entry)
+		if err != nil {
+			return err
+		}
+		bodies = append(bodies, body)
+	}
+
+	for start := 0; start < len(bodies); {
+		end, size := start, 0
+		for end < len(bodies) && end-start < sqsBatchSize && size+len(bodies[end]) <= sqsMaxBytes {
+			size += len(bodies[end])
+			end++
+		}
+
+		input := &sqs.SendMessageBatchInput{QueueUrl: aws.String(s.queueURL)}
+		for i, body := range bodies[start:end] {
+			input.Entries = append(input.Entries, &sqs.SendMessageBatchRequestEntry{
+				Id:          aws.String(fmt.Sprintf("%d", i)),
+				MessageBody: aws.String(body),
+			})
+		}
+
+		out, err := s.client.SendMessageBatch(input)
+		if err != nil {
+			return err
+		}
+		if len(out.Failed) > 0 {
+			return fmt.Errorf("failed to send %d dead letter messages: %s",
+				len(out.Failed), aws.StringValue(out.Failed[0].Message))
+		}
+		start = end
+	}
+	return nil
+}
+
+// sqsBody encodes an entry as a message body. An entry too large for a message is sent without its
+// document, as replay converts the record again anyway.
+func sqsBody(entry Entry) (string, error) {
+	body, err := json.Marshal(entry)
+	if err == nil && len(body) > sqsMaxBytes {
+		entry.Doc = es.Doc{}
+		body, err = json.Marshal(entry)
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(body) > sqsMaxBytes {
+		return "", fmt.Errorf("dead letter entry of record %s is %d bytes, more than an SQS message holds",
+			entry.Record.EventID, len(body))
+	}
+	return string(body), nil
+}
+
+// SQSQueue receives the entries an SQSSink sent, so they can be replayed
+type SQSQueue struct {
+	client   sqsiface.SQSAPI
+	queueURL string
+}
+
+// NewSQSQueue creates an SQSQueue receiving from the queue at queueURL
+func NewSQSQueue(client sqsiface.SQSAPI, queueURL string) *SQSQueue {
+	return &SQSQueue{client: client, queueURL: queueURL}
+}
+
+// Message is an entry received from an SQSQueue
+type Message struct {
+	Entry Entry
+	// Sent is when the entry was sent to the queue
+	Sent          time.Time
+	receiptHandle string
+}
+
+// Receive returns the next messages of the queue, or none once it's empty. Messages received are
+// hidden from later calls until the queue's visibility timeout passes, unless they're deleted.
+func (q *SQSQueue) Receive() ([]Message, error) {
+	out, err := q.client.ReceiveMessage(&sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(q.queueURL),
+		MaxNumberOfMessages: aws.Int64(sqsBatchSize),
+		// long polling asks every server, so an empty response means the queue is empty
+		WaitTimeSeconds: aws.Int64(1),
+		AttributeNames:  []*string{aws.String(sqs.MessageSystemAttributeNameSentTimestamp)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []Message{}
+	for _, message := range out.Messages {
+		entry := Entry{}
+		if err := json.Unmarshal([]byte(aws.StringValue(message.Body)), &entry); err != nil {
+			return nil, fmt.Errorf("message %s: %s", aws.StringValue(message.MessageId), err)
+		}
+		sent, err := strconv.ParseInt(aws.StringValue(message.Attributes[sqs.MessageSystemAttributeNameSentTimestamp]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("message %s has an invalid sent timestamp: %s", aws.StringValue(message.MessageId), err)
+		}
+		messages = append(messages, Message{
+			Entry:         entry,
+			Sent:          time.Unix(0, sent*int64(time.Millisecond)),
+			receiptHandle: aws.StringValue(message.ReceiptHandle),
+		})
+	}
+	return messages, nil
+}
+
+// Delete removes messages from the queue, once they've been replayed
+func (q *SQSQueue) Delete(messages []Message) error {
+	for start := 0; start < len(messages); start += sqsBatchSize {
+		end := start + sqsBatchSize
+		if end > len(messages) {
+			end = len(messages)
+		}
+
+		input := &sqs.DeleteMessageBatchInput{QueueUrl: aws.String(q.queueURL)}
+		for i, message := range messages[start:end] {
+			input.Entries = append(input.Entries, &sqs.DeleteMessageBatchRequestEntry{
+				Id:            aws.String(fmt.Sprintf("%d", i)),
+				ReceiptHandle: aws.String(message.receiptHandle),
+			})
+		}
+
+		out, err := q.client.DeleteMessageBatch(input)
+		if err != nil {
+			return err
+		}
+		if len(out.Failed) > 0 {
+			return fmt.Errorf("failed to delete %d dead letter messages: %s",
+				len(out.Failed), aws.StringValue(out.Failed[0].Message))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package deadletter
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/Clever/ddb-to-es/es"
+)
+
+// Entry is a document Elasticsearch permanently rejected, along with the
+// DynamoDB record it was converted from so it can be replayed
+type Entry struct {
+	Record    events.DynamoDBEventRecord `json:"record"`
+	Doc       es.Doc                     `json:"doc"`
+	Index     string                     `json:"index"`
+	Status    int                        `json:"status"`
+	ErrorType string                     `json:"errorType"`
+	Reason    string                     `json:"reason"`
+	Time      time.Time                  `json:"time"`
+}
+
+// Sink receives documents that could not be written to Elasticsearch
+type Sink interface {
+	Send([]Entry) error
+}
+
+// New creates a Sink from a URL:
+//   - file:///path/to/dir writes JSON lines files to a local directory
+//   - s3://bucket/prefix writes JSON lines objects to an S3 bucket
+//   - sqs://host/account/queue sends each entry as a message to the SQS queue https://host/account/queue
+//
+// endpoint optionally overrides the AWS endpoint, for S3 or SQS compatible services.
+func New(rawURL, endpoint string) (Sink, error) {
+	if err := ValidateURL(rawURL); err != nil {
+		return nil, err
+	}
+	u, _ := url.Parse(rawURL)
+
+	switch u.Scheme {
+	case "", "file":
+		return NewDirSink(u.Path)
+	case "s3":
+		return NewS3Sink(newS3Client(endpoint), u.Host, strings.TrimPrefix(u.Path, "/")), nil
+	case "sqs":
+		return NewSQSSink(newSQSClient(endpoint), fmt.Sprintf("https://%s%s", u.Host, u.Path)), nil
+	default:
+		return nil, fmt.Errorf("unsupported dead letter URL scheme %s", u.Scheme)
+	}
+}
+
+// Open opens a file of entries written by a Sink, from a URL:
+//   - file:///path/to/file.jsonl, or a path, opens a local file
+//   - s3://bucket/key opens an S3 object
+//
+// endpoint optionally overrides the AWS endpoint, for S3 compatible services.
+func Open(rawURL, endpoint string) (io.ReadCloser, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dead letter file URL %s: %s", rawURL, err)
+	}
+
+	switch u.Scheme {
+	case "", "file":
+		return os.Open(u.Path)
+	case "s3":
+		if u.Host == "" || u.Path == "" {
+			return nil, fmt.Errorf("dead letter file URL %s is missing a bucket or key", rawURL)
+		}
+		return openS3Object(newS3Client(endpoint), u.Host, strings.TrimPrefix(u.Path, "/"))
+	default:
+		return nil, fmt.Errorf("unsupported dead letter file URL scheme %s", u.Scheme)
+	}
+}
+
+// OpenQueue opens a queue of entries sent by a Sink, from a URL of the form sqs://host/account/queue
+// as given to New. endpoint optionally overrides the AWS endpoint, for SQS compatible services.
+func OpenQueue(rawURL, endpoint string) (*SQSQueue, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dead letter queue URL %s: %s", rawURL, err)
+	}
+	if u.Scheme != "sqs" {
+		return nil, fmt.Errorf("unsupported dead letter queue URL scheme %s", u.Scheme)
+	}
+	if u.Host == "" || u.Path == "" {
+		return nil, fmt.Errorf("dead letter queue URL %s is missing a queue", rawURL)
+	}
+	return NewSQSQueue(newSQSClient(endpoint), fmt.Sprintf("https://%s%s", u.Host, u.Path)), nil
+}
+
+// IsQueue returns true if the URL is of a queue to open with OpenQueue, rather than a file to Open
+func IsQueue(rawURL string) bool {
+	return strings.HasPrefix(rawURL, "sqs://")
+}
+
+// ValidateURL checks that a Sink can be created from the URL
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid dead letter URL %s: %s", rawURL, err)
+	}
+
+	switch u.Scheme {
+	case "", "file":
+		if u.Path == "" {
+			return fmt.Errorf("dead letter URL %s is missing a directory", rawURL)
+		}
+	case "s3":
+		if u.Host == "" {
+			return fmt.Errorf("dead letter URL %s is missing a bucket", rawURL)
+		}
+	case "sqs":
+		if u.Host == "" || u.Path == "" {
+			return fmt.Errorf("dead letter URL %s is missing a queue", rawURL)
+		}
+	default:
+		return fmt.Errorf("unsupported dead letter URL scheme %s", u.Scheme)
+	}
+	return nil
+}
+
+// encode writes the entries as JSON lines
+func encode(w io.Writer, entries []Entry) error {
+	enc := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decode reads JSON lines entries, as written by a Sink
+func Decode(r io.Reader) ([]Entry, error) {
+	entries := []Entry{}
+	scanner := bufio.NewScanner(r)
+	// DynamoDB items are at most 400KB, but are larger once converted to JSON
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		entry := Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// objectName returns a unique name for a batch of entries sent at t
+func objectName(t time.Time) string {
+	return fmt.Sprintf("%s-%08x.jsonl", t.UTC().Format("20060102T150405.000000000Z"), rand.Uint32())
+}
@@ -0,0 +1,242 @@
+package deadletter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Clever/ddb-to-es/es"
+)
+
+func testEntry(id string) Entry {
+	return Entry{
+		Record: events.DynamoDBEventRecord{
+			EventID:   id,
+			EventName: "INSERT",
+			Change: events.DynamoDBStreamRecord{
+				ApproximateCreationDateTime: events.SecondsEpochTime{Time: time.Unix(1480642020, 0)},
+				Keys:                        map[string]events.DynamoDBAttributeValue{"id": events.NewStringAttribute(id)},
+				SequenceNumber:              "100",
+			},
+		},
+		Doc:       es.Doc{Op: es.OpTypeInsert, ID: id, Item: map[string]interface{}{"id": id}},
+		Index:     "test-index",
+		Status:    400,
+		ErrorType: "mapper_parsing_exception",
+		Reason:    "failed to parse",
+		Time:      time.Date(2024, 7, 16, 15, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestDirSink(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := New("file://"+dir, "")
+	require.NoError(t, err)
+
+	entries := []Entry{testEntry("a"), testEntry("b")}
+	require.NoError(t, sink.Send(entries))
+	require.NoError(t, sink.Send(nil))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	f, err := Open(files[0], "")
+	require.NoError(t, err)
+	defer f.Close()
+	decoded, err := Decode(f)
+	require.NoError(t, err)
+	assert.Equal(t, entries, decoded)
+}
+
+type mockSQS struct {
+	sqsiface.SQSAPI
+	batches []*sqs.SendMessageBatchInput
+	// queued holds the messages sent and not yet received, while received holds those received
+	// and not yet deleted, by receipt handle
+	queued   []*sqs.Message
+	received map[string]*sqs.Message
+}
+
+func (m *mockSQS) SendMessageBatch(input *sqs.SendMessageBatchInput) (*sqs.SendMessageBatchOutput, error) {
+	m.batches = append(m.batches, input)
+	for _, entry := range input.Entries {
+		handle := fmt.Sprintf("handle-%d", len(m.queued)+len(m.received))
+		m.queued = append(m.queued, &sqs.Message{
+			MessageId:     aws.String(handle),
+			ReceiptHandle: aws.String(handle),
+			Body:          entry.MessageBody,
+			Attributes: map[string]*string{
+				sqs.MessageSystemAttributeNameSentTimestamp: aws.String("1721142000000"),
+			},
+		})
+	}
+	return &sqs.SendMessageBatchOutput{}, nil
+}
+
+func (m *mockSQS) ReceiveMessage(input *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+	n := int(aws.Int64Value(input.MaxNumberOfMessages))
+	if n > len(m.queued) {
+		n = len(m.queued)
+	}
+	out := &sqs.ReceiveMessageOutput{Messages: m.queued[:n]}
+	if m.received == nil {
+		m.received = map[string]*sqs.Message{}
+	}
+	for _, message := range m.queued[:n] {
+		m.received[aws.StringValue(message.ReceiptHandle)] = message
+	}
+	m.queued = m.queued[n:]
+	return out, nil
+}
+
+func (m *mockSQS) DeleteMessageBatch(input *sqs.DeleteMessageBatchInput) (*sqs.DeleteMessageBatchOutput, error) {
+	for _, entry := range input.Entries {
+		delete(m.received, aws.StringValue(entry.ReceiptHandle))
+	}
+	return &sqs.DeleteMessageBatchOutput{}, nil
+}
+
+func TestSQSSink(t *testing.T) {
+	client := &mockSQS{}
+	sink := NewSQSSink(client, "https://sqs.us-west-2.amazonaws.com/123456789012/dead-letter")
+
+	entries := []Entry{}
+	for i := 0; i < 12; i++ {
+		entries = append(entries, testEntry(string(rune('a'+i))))
+	}
+	require.NoError(t, sink.Send(entries))
+
+	require.Len(t, client.batches, 2)
+	assert.Len(t, client.batches[0].Entries, 10)
+	assert.Len(t, client.batches[1].Entries, 2)
+	assert.Equal(t, "https://sqs.us-west-2.amazonaws.com/123456789012/dead-letter", aws.StringValue(client.batches[1].QueueUrl))
+
+	entry := Entry{}
+	require.NoError(t, json.Unmarshal([]byte(aws.StringValue(client.batches[1].Entries[1].MessageBody)), &entry))
+	assert.Equal(t, entries[11], entry)
+}
+
+func TestSQSSinkLimitsBatchSize(t *testing.T) {
+	client := &mockSQS{}
+	sink := NewSQSSink(client, "https://sqs.us-west-2.amazonaws.com/123456789012/dead-letter")
+
+	// entries of 100KB only fit two to a batch
+	large := func(id string) Entry {
+		entry := testEntry(id)
+		entry.Record.Change.NewImage = map[string]events.DynamoDBAttributeValue{
+			"data": events.NewStringAttribute(strings.Repeat("x", 100*1024)),
+		}
+		return entry
+	}
+	require.NoError(t, sink.Send([]Entry{large("a"), large("b"), large("c"), testEntry("d")}))
+	require.Len(t, client.batches, 2)
+	assert.Len(t, client.batches[0].Entries, 2)
+	assert.Len(t, client.batches[1].Entries, 2)
+
+	// an entry too large for a message is sent without its document, which replay doesn't need
+	client.batches = nil
+	entry := large("e")
+	entry.Doc.Item = map[string]interface{}{"data": strings.Repeat("x", 200*1024)}
+	require.NoError(t, sink.Send([]Entry{entry}))
+	require.Len(t, client.batches, 1)
+	sent := Entry{}
+	require.NoError(t, json.Unmarshal([]byte(aws.StringValue(client.batches[0].Entries[0].MessageBody)), &sent))
+	assert.Equal(t, entry.Record, sent.Record)
+	assert.Empty(t, sent.Doc.ID)
+
+	entry.Record.Change.OldImage = entry.Record.Change.NewImage
+	entry.Record.Change.NewImage = map[string]events.DynamoDBAttributeValue{
+		"data": events.NewStringAttribute(strings.Repeat("x", 200*1024)),
+	}
+	assert.Error(t, sink.Send([]Entry{entry}))
+}
+
+func TestSQSQueueReceivesSentEntries(t *testing.T) {
+	client := &mockSQS{}
+	queueURL := "https://sqs.us-west-2.amazonaws.com/123456789012/dead-letter"
+	entries := []Entry{}
+	for i := 0; i < 12; i++ {
+		entries = append(entries, testEntry(string(rune('a'+i))))
+	}
+	require.NoError(t, NewSQSSink(client, queueURL).Send(entries))
+
+	queue := NewSQSQueue(client, queueURL)
+	received := []Entry{}
+	for {
+		messages, err := queue.Receive()
+		require.NoError(t, err)
+		if len(messages) == 0 {
+			break
+		}
+		for _, message := range messages {
+			received = append(received, message.Entry)
+			assert.True(t, time.Date(2024, 7, 16, 15, 0, 0, 0, time.UTC).Equal(message.Sent))
+		}
+		require.NoError(t, queue.Delete(messages))
+	}
+	assert.Equal(t, entries, received)
+	assert.Empty(t, client.received)
+
+	assert.True(t, IsQueue("sqs://sqs.us-west-2.amazonaws.com/123456789012/dead-letter"))
+	assert.False(t, IsQueue("s3://bucket/key"))
+	_, err := OpenQueue("sqs://sqs.us-west-2.amazonaws.com", "")
+	assert.Error(t, err)
+}
+
+type mockS3 struct {
+	s3iface.S3API
+	objects map[string][]byte
+}
+
+func (m *mockS3) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	body, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.objects[aws.StringValue(input.Bucket)+"/"+aws.StringValue(input.Key)] = body
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *mockS3) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	body, ok := m.objects[aws.StringValue(input.Bucket)+"/"+aws.StringValue(input.Key)]
+	if !ok {
+		return nil, fmt.Errorf("NoSuchKey: %s", aws.StringValue(input.Key))
+	}
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader(body))}, nil
+}
+
+func TestS3SinkObjectsOpen(t *testing.T) {
+	client := &mockS3{objects: map[string][]byte{}}
+	sink := NewS3Sink(client, "bucket", "dead-letter")
+
+	entries := []Entry{testEntry("a"), testEntry("b")}
+	require.NoError(t, sink.Send(entries))
+	require.Len(t, client.objects, 1)
+
+	for name := range client.objects {
+		f, err := openS3Object(client, "bucket", strings.TrimPrefix(name, "bucket/"))
+		require.NoError(t, err)
+		defer f.Close()
+		decoded, err := Decode(f)
+		require.NoError(t, err)
+		assert.Equal(t, entries, decoded)
+	}
+
+	_, err := Open("ftp://example.com/file.jsonl", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,41 @@
+package deadletter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DirSink writes each batch of entries to a new JSON lines file in a local directory
+type DirSink struct {
+	dir string
+}
+
+// NewDirSink creates a DirSink, creating the directory if it doesn't exist
+func NewDirSink(dir string) (*DirSink, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("missing dead letter directory")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create dead letter directory: %s", err)
+	}
+	return &DirSink{dir: dir}, nil
+}
+
+// Send implements Sink
+func (s *DirSink) Send(entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	f, err := os.Create(filepath.Join(s.dir, objectName(time.Now())))
+	if err != nil {
+		return err
+	}
+	if err := encode(f, entries); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,232 @@
+package es
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/Clever/kayvee-go.v6/logger"
+)
+
+// AliasConfig specifies how documents are written through write aliases, each an alias of one
+// versioned index such as "users-v1". Indices are reindexed into a new version by Reindex, during
+// which writers also write to the new index.
+type AliasConfig struct {
+	// RefreshInterval is how often writers check for indices being reindexed into
+	RefreshInterval time.Duration
+}
+
+// reindexSuffix names the alias of the index being reindexed into, such as "users-reindex"
+const reindexSuffix = "-reindex"
+
+// reindexError is the error type of partial updates of an index being reindexed into. Written as
+// upserts, they would create documents of only the changed attributes, which _reindex then skips
+// rather than copying the full document.
+const reindexError = "partial_update_while_reindexing_exception"
+
+// versionSuffix matches the version of a versioned index, such as the "-v2" of "users-v2"
+var versionSuffix = regexp.MustCompile(`-v([0-9]+)$`)
+
+// aliasState caches the indices being reindexed into by writers
+type aliasState struct {
+	// reindexing maps each alias to the indices being reindexed into for it
+	reindexing  map[string][]string
+	refreshedAt time.Time
+}
+
+// ReindexResult reports a completed reindex
+type ReindexResult struct {
+	Alias string
+	// From is the index the alias pointed to, and To the index it points to now
+	From string
+	To   string
+	// Created is the number of documents copied, and Conflicts the number already written by writers
+	Created   int
+	Conflicts int
+}
+
+// Reindexer is implemented by DBs that can reindex the indices behind write aliases
+type Reindexer interface {
+	// Reindex creates the next version of the index behind a write alias from the template, copies
+	// the documents of the current index to it, and atomically points the alias at it.
+	// Writers also write to the new index from when it's created, so no writes are missed.
+	// It requires DBConfig.VersionType or soft deletes in the index, so that documents deleted while
+	// copying aren't restored.
+	Reindex(alias string, template Template) (ReindexResult, error)
+}
+
+// Reindex implements Reindexer
+func (db *writer) Reindex(alias string, template Template) (ReindexResult, error) {
+	alias = strings.ToLower(alias)
+	result := ReindexResult{Alias: alias}
+	// a document deleted while copying is missing from the new index, so the copy would restore it
+	// unless the delete left a newer version or a soft deleted document behind
+	if db.config.VersionType == "" && !db.softDeletesAlias(alias) {
+		return result, fmt.Errorf("%s can't be reindexed without versionType or soft deletes, as documents deleted while copying would be restored", alias)
+	}
+	aliases, err := db.getAliases()
+	if err != nil {
+		return result, err
+	}
+	current := aliases[alias]
+	if len(current) != 1 {
+		return result, fmt.Errorf("%s must be an alias of one index, not %d", alias, len(current))
+	}
+	result.From = current[0]
+
+	// resume a reindex that was interrupted before the alias was flipped
+	if next := aliases[alias+reindexSuffix]; len(next) == 1 {
+		result.To = next[0]
+		db.lg.InfoD("reindex-resumed", logger.M{"alias": alias, "index": result.To})
+	} else {
+		result.To = nextVersion(alias, result.From)
+		create := map[string]interface{}{
+			"mappings": db.typedMappings(template.Mappings),
+			"aliases":  map[string]interface{}{alias + reindexSuffix: map[string]interface{}{}},
+		}
+		if len(template.Settings) > 0 {
+			create["settings"] = template.Settings
+		}
+		if _, _, err := db.perform(http.MethodPut, "/"+url.PathEscape(result.To), create); err != nil {
+			return result, err
+		}
+		db.lg.InfoD("index-created", logger.M{"index": result.To, "alias": alias + reindexSuffix})
+	}
+
+	// wait for every writer to notice the new index, and for writes in flight when they did to finish,
+	// so that documents changed while copying are written to both indices
+	if db.config.Aliases != nil {
+		db.sleep(2 * db.config.Aliases.RefreshInterval)
+	}
+
+	// documents already written by writers are newer than the copies, so are kept
+	dest := map[string]interface{}{"index": result.To, "op_type": "create"}
+	if db.config.VersionType != "" {
+		dest = map[string]interface{}{"index": result.To, "version_type": "external"}
+	}
+	_, body, err := db.perform(http.MethodPost, "/_reindex?wait_for_completion=true&refresh=true", map[string]interface{}{
+		"conflicts": "proceed",
+		"source":    map[string]interface{}{"index": result.From},
+		"dest":      dest,
+	})
+	if err != nil {
+		return result, err
+	}
+	resp := struct {
+		Created          int               `json:"created"`
+		VersionConflicts int               `json:"version_conflicts"`
+		Failures         []json.RawMessage `json:"failures"`
+	}{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return result, fmt.Errorf("invalid reindex response: %s", err)
+	}
+	result.Created, result.Conflicts = resp.Created, resp.VersionConflicts
+	if len(resp.Failures) > 0 {
+		return result, fmt.Errorf("%d documents could not be copied, the first failing with %s", len(resp.Failures), resp.Failures[0])
+	}
+
+	_, _, err = db.perform(http.MethodPost, "/_aliases", map[string]interface{}{
+		"actions": []interface{}{
+			map[string]interface{}{"add": map[string]interface{}{"index": result.To, "alias": alias}},
+			map[string]interface{}{"remove": map[string]interface{}{"index": result.From, "alias": alias}},
+			map[string]interface{}{"remove": map[string]interface{}{"index": result.To, "alias": alias + reindexSuffix}},
+		},
+	})
+	if err != nil {
+		return result, err
+	}
+	db.lg.InfoD("alias-flipped", logger.M{"alias": alias, "from": result.From, "to": result.To})
+	return result, nil
+}
+
+// softDeletesAlias returns true if deletes mark documents as deleted in the index behind the alias
+func (db *writer) softDeletesAlias(alias string) bool {
+	for _, index := range db.config.SoftDeleteIndices {
+		if strings.EqualFold(index, alias) {
+			return true
+		}
+	}
+	return false
+}
+
+// reindexing returns the indices being reindexed into for each alias, refreshed every
+// AliasConfig.RefreshInterval. Indices the alias already points to are left out, so documents
+// aren't written to them twice after the alias is flipped.
+func (db *writer) reindexing() (map[string][]string, error) {
+	db.aliasMu.Lock()
+	defer db.aliasMu.Unlock()
+	if db.aliases.reindexing != nil && time.Since(db.aliases.refreshedAt) < db.config.Aliases.RefreshInterval {
+		return db.aliases.reindexing, nil
+	}
+
+	aliases, err := db.getAliases()
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve aliases: %s", err)
+	}
+	reindexing := map[string][]string{}
+	for name, indices := range aliases {
+		alias := strings.TrimSuffix(name, reindexSuffix)
+		if alias == name {
+			continue
+		}
+		for _, index := range indices {
+			if !contains(aliases[alias], index) {
+				reindexing[alias] = append(reindexing[alias], index)
+			}
+		}
+	}
+	db.aliases = aliasState{reindexing: reindexing, refreshedAt: time.Now()}
+	return reindexing, nil
+}
+
+// getAliases returns the indices of every alias, in order
+func (db *writer) getAliases() (map[string][]string, error) {
+	status, body, err := db.perform(http.MethodGet, "/_alias/*", nil)
+	if err != nil {
+		return nil, err
+	}
+	aliases := map[string][]string{}
+	if status == http.StatusNotFound {
+		return aliases, nil
+	}
+	resp := map[string]struct {
+		Aliases map[string]json.RawMessage `json:"aliases"`
+	}{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid alias response: %s", err)
+	}
+	for index, i := range resp {
+		for alias := range i.Aliases {
+			aliases[alias] = append(aliases[alias], index)
+		}
+	}
+	for _, indices := range aliases {
+		sort.Strings(indices)
+	}
+	return aliases, nil
+}
+
+// nextVersion returns the name of the next version of the index behind an alias, such as "users-v3"
+// for "users-v2". Indices without a version are followed by the second.
+func nextVersion(alias, index string) string {
+	version := 1
+	if match := versionSuffix.FindStringSubmatch(index); match != nil {
+		version, _ = strconv.Atoi(match[1])
+	}
+	return fmt.Sprintf("%s-v%d", alias, version+1)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,13 @@
+package es
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextVersion(t *testing.T) {
+	assert.Equal(t, "users-v2", nextVersion("users", "users-v1"))
+	assert.Equal(t, "users-v11", nextVersion("users", "users-v10"))
+	assert.Equal(t, "users-v2", nextVersion("users", "users_2019"))
+}
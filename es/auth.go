@@ -0,0 +1,144 @@
+package es
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+// AuthConfig specifies how requests to Elasticsearch are authenticated. At most one of basic auth,
+// an API key or AWS signing may be used.
+type AuthConfig struct {
+	Username string
+	Password string
+	// APIKey is the base64 encoded "id:api_key" of an Elasticsearch API key
+	APIKey string
+	// AWSSigV4 signs requests for Amazon OpenSearch Service, with credentials from the
+	// standard AWS environment variables, shared config or instance role
+	AWSSigV4 bool
+	// AWSRegion of the domain. Defaults to AWS_REGION.
+	AWSRegion string
+	// AWSService signed for, es for OpenSearch Service domains or aoss for serverless collections
+	AWSService string
+}
+
+// TLSConfig specifies how the TLS connection to Elasticsearch is verified and authenticated
+type TLSConfig struct {
+	// CAFile is a PEM bundle of the certificate authorities trusted, in place of the system's
+	CAFile string
+	// CertFile and KeyFile are the PEM client certificate and key presented to the cluster
+	CertFile string
+	KeyFile  string
+}
+
+// httpClient returns the http.Client for the config's TLS and authentication
+func httpClient(config *DBConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig, err := config.TLS.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	var rt http.RoundTripper = transport
+	auth := config.Auth
+	if auth.APIKey != "" {
+		rt = &headerTransport{next: rt, header: "Authorization", value: "ApiKey " + auth.APIKey}
+	}
+	if auth.AWSSigV4 {
+		sess, err := session.NewSession()
+		if err != nil {
+			return nil, fmt.Errorf("could not load AWS credentials: %s", err)
+		}
+		region := auth.AWSRegion
+		if region == "" && sess.Config.Region != nil {
+			region = *sess.Config.Region
+		}
+		if region == "" {
+			return nil, fmt.Errorf("AWS signing requires a region")
+		}
+		service := auth.AWSService
+		if service == "" {
+			service = "es"
+		}
+		rt = &signingTransport{
+			next:    rt,
+			signer:  v4.NewSigner(sess.Config.Credentials),
+			region:  region,
+			service: service,
+		}
+	}
+	return &http.Client{Transport: rt}, nil
+}
+
+// tlsConfig returns the tls.Config for the CA bundle and client certificate, or nil for the defaults
+func (c TLSConfig) tlsConfig() (*tls.Config, error) {
+	if c.CAFile == "" && c.CertFile == "" {
+		return nil, nil
+	}
+	config := &tls.Config{}
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA bundle: %s", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", c.CAFile)
+		}
+	}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %s", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// headerTransport sets a header on every request
+type headerTransport struct {
+	next   http.RoundTripper
+	header string
+	value  string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// requests must not be modified by a RoundTripper
+	req = req.Clone(req.Context())
+	req.Header.Set(t.header, t.value)
+	return t.next.RoundTrip(req)
+}
+
+// signingTransport signs every request with AWS Signature Version 4
+type signingTransport struct {
+	next    http.RoundTripper
+	signer  *v4.Signer
+	region  string
+	service string
+}
+
+func (t *signingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	body := []byte{}
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	// the signer sets the body from the seeker it hashes
+	if _, err := t.signer.Sign(req, bytes.NewReader(body), t.service, t.region, time.Now()); err != nil {
+		return nil, fmt.Errorf("could not sign request: %s", err)
+	}
+	return t.next.RoundTrip(req)
+}
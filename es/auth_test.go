@@ -0,0 +1,103 @@
+package es
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/Clever/kayvee-go.v6/logger"
+)
+
+// setenv sets an environment variable for the rest of the test
+func setenv(t *testing.T, key, value string) {
+	prev, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// writeDoc writes a single document to db, failing the test if it isn't written
+func writeDoc(t *testing.T, db *Elasticsearch) {
+	result, err := db.WriteDocs([]Doc{{Op: OpTypeInsert, ID: "1", Item: map[string]interface{}{"a": "b"}}})
+	require.NoError(t, err)
+	require.Empty(t, result.Failed())
+}
+
+func TestAuth(t *testing.T) {
+	tests := []struct {
+		name   string
+		auth   AuthConfig
+		header string
+	}{
+		{"none", AuthConfig{}, ""},
+		{"basic", AuthConfig{Username: "user", Password: "pass"}, "Basic dXNlcjpwYXNz"},
+		{"api key", AuthConfig{APIKey: "aWQ6a2V5"}, "ApiKey aWQ6a2V5"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := newFakeES(t, nil)
+			db, err := NewDB(&DBConfig{URL: fake.URL, Auth: test.auth}, []string{"test-index"}, logger.New("test"))
+			require.NoError(t, err)
+			writeDoc(t, db)
+
+			headers := fake.Headers()
+			require.NotEmpty(t, headers)
+			for _, header := range headers {
+				assert.Equal(t, test.header, header.Get("Authorization"))
+			}
+		})
+	}
+}
+
+func TestAuthAWSSigV4(t *testing.T) {
+	setenv(t, "AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	setenv(t, "AWS_SECRET_ACCESS_KEY", "secret")
+	setenv(t, "AWS_SESSION_TOKEN", "token")
+	setenv(t, "AWS_REGION", "us-west-2")
+
+	fake := newFakeES(t, nil)
+	db, err := NewDB(&DBConfig{URL: fake.URL, Auth: AuthConfig{AWSSigV4: true}}, []string{"test-index"}, logger.New("test"))
+	require.NoError(t, err)
+	writeDoc(t, db)
+
+	headers := fake.Headers()
+	require.NotEmpty(t, headers)
+	for _, header := range headers {
+		assert.True(t, strings.HasPrefix(header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"))
+		assert.Contains(t, header.Get("Authorization"), "/us-west-2/es/aws4_request")
+		assert.Equal(t, "token", header.Get("X-Amz-Security-Token"))
+	}
+	// the body is still sent after it is hashed
+	require.Len(t, fake.Bulks(), 1)
+	assert.Equal(t, "1", fake.Bulks()[0][0].ID())
+}
+
+func TestTLS(t *testing.T) {
+	fake := &fakeES{}
+	fake.Server = httptest.NewTLSServer(http.HandlerFunc(fake.serveHTTP))
+	t.Cleanup(fake.Close)
+
+	// the test server's certificate isn't trusted by the system
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: fake.Certificate().Raw})
+	require.NoError(t, ioutil.WriteFile(caFile, ca, 0600))
+	db, err := NewDB(&DBConfig{URL: fake.URL, TLS: TLSConfig{CAFile: caFile}}, []string{"test-index"}, logger.New("test"))
+	require.NoError(t, err)
+	writeDoc(t, db)
+
+	_, err = NewDB(&DBConfig{URL: fake.URL, TLS: TLSConfig{CertFile: "missing.pem", KeyFile: "missing.key"}},
+		[]string{"test-index"}, logger.New("test"))
+	assert.Error(t, err)
+}
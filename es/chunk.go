@@ -0,0 +1,106 @@
+package es
+
+import (
+	"sync"
+
+	elastic "gopkg.in/olivere/elastic.v6"
+)
+
+// BulkConfig specifies how actions are split into bulk requests, so that none exceeds the
+// cluster's http.max_content_length
+type BulkConfig struct {
+	// MaxActions per bulk request. Unlimited if zero.
+	MaxActions int
+	// MaxBytes per bulk request, estimated from the size of each action. An action larger than
+	// it is sent on its own. Unlimited if zero.
+	MaxBytes int
+	// Concurrency is how many bulk requests are sent at once. Defaults to one.
+	Concurrency int
+}
+
+// requestSize returns the number of bytes a request adds to a bulk request body
+func requestSize(req elastic.BulkableRequest) (int, error) {
+	lines, err := req.Source()
+	if err != nil {
+		return 0, err
+	}
+	size := 0
+	for _, line := range lines {
+		size += len(line) + 1
+	}
+	return size, nil
+}
+
+// chunks splits the positions in actions of the pending actions into bulk requests
+func (c BulkConfig) chunks(pending []int, actions []bulkAction) [][]int {
+	chunks := [][]int{}
+	chunk, size := []int{}, 0
+	for _, i := range pending {
+		full := c.MaxActions > 0 && len(chunk) >= c.MaxActions
+		tooBig := c.MaxBytes > 0 && size+actions[i].size > c.MaxBytes
+		if len(chunk) > 0 && (full || tooBig) {
+			chunks = append(chunks, chunk)
+			chunk, size = []int{}, 0
+		}
+		chunk = append(chunk, i)
+		size += actions[i].size
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// sendChunks sends each chunk of actions as a bulk request, up to Bulk.Concurrency at once, and
+// returns their responses. A chunk writing a document an earlier chunk writes too is only sent once
+// the earlier one has been, so that writes of the same document stay in order.
+// An error is returned if any of them could not be sent.
+func (db *writer) sendChunks(chunks [][]int, actions []bulkAction) ([]*elastic.BulkResponse, error) {
+	concurrency := db.config.Bulk.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	responses := make([]*elastic.BulkResponse, len(chunks))
+	errs := make([]error, len(chunks))
+	sent := make([]chan struct{}, len(chunks))
+	// the last chunk writing each document
+	lastChunk := map[string]int{}
+	wg := sync.WaitGroup{}
+	slots := make(chan struct{}, concurrency)
+	for c, chunk := range chunks {
+		reqs := make([]elastic.BulkableRequest, len(chunk))
+		after := map[int]bool{}
+		for j, i := range chunk {
+			reqs[j] = actions[i].req
+			key := actions[i].key()
+			if last, ok := lastChunk[key]; ok && last != c {
+				after[last] = true
+			}
+			lastChunk[key] = c
+		}
+		sent[c] = make(chan struct{})
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(c int, after map[int]bool) {
+			defer func() {
+				close(sent[c])
+				<-slots
+				wg.Done()
+			}()
+			// earlier chunks hold their own slots, so they're never waiting on this one
+			for last := range after {
+				<-sent[last]
+			}
+			responses[c], errs[c] = db.send(reqs)
+		}(c, after)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return responses, nil
+}
@@ -0,0 +1,97 @@
+package es
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/Clever/kayvee-go.v6/logger"
+	elastic "gopkg.in/olivere/elastic.v6"
+)
+
+func TestBulkChunks(t *testing.T) {
+	actions := []bulkAction{{size: 10}, {size: 10}, {size: 30}, {size: 50}, {size: 10}}
+	pending := []int{0, 1, 2, 3, 4}
+
+	assert.Equal(t, [][]int{{0, 1, 2, 3, 4}}, BulkConfig{}.chunks(pending, actions))
+	assert.Equal(t, [][]int{{0, 1}, {2, 3}, {4}}, BulkConfig{MaxActions: 2}.chunks(pending, actions))
+	// the 50 byte action is larger than the limit, so it is sent on its own
+	assert.Equal(t, [][]int{{0, 1}, {2}, {3}, {4}}, BulkConfig{MaxBytes: 40}.chunks(pending, actions))
+	assert.Equal(t, [][]int{{0, 2}, {3}, {4}}, BulkConfig{MaxActions: 3, MaxBytes: 40}.chunks([]int{0, 2, 3, 4}, actions))
+}
+
+func TestWriteDocsChunks(t *testing.T) {
+	for _, impl := range []string{APIv6, APITypeless} {
+		t.Run(impl, func(t *testing.T) {
+			fake := implementations[impl].newFake(t, func(action fakeBulkAction) (int, string) {
+				if action.ID() == "bad" {
+					return 400, "mapper_parsing_exception"
+				}
+				return 201, ""
+			})
+			db := mustOpen(t, implementations[impl], &DBConfig{
+				URL:  fake.URL,
+				Bulk: BulkConfig{MaxActions: 3, MaxBytes: 1000, Concurrency: 2},
+			}, []string{"index-1", "index-2"})
+
+			docs := []Doc{}
+			for i := 0; i < 5; i++ {
+				docs = append(docs, Doc{Op: OpTypeInsert, ID: fmt.Sprint(i), Item: map[string]interface{}{"a": "b"}})
+			}
+			docs[3].ID = "bad"
+			// larger than MaxBytes on its own
+			docs = append(docs, Doc{Op: OpTypeInsert, ID: "big", Item: map[string]interface{}{"a": strings.Repeat("b", 1000)}})
+
+			result, err := db.WriteDocs(docs)
+			require.NoError(t, err)
+			assert.Equal(t, []int{3}, result.Failed())
+			for i, doc := range result.Docs {
+				assert.Equal(t, docs[i].ID, doc.ID)
+				require.Len(t, doc.Indices, 2)
+				assert.Equal(t, "index-1", doc.Indices[0].Index)
+				assert.Equal(t, "index-2", doc.Indices[1].Index)
+			}
+
+			sizes := []int{}
+			for _, bulk := range fake.Bulks() {
+				sizes = append(sizes, len(bulk))
+			}
+			sort.Ints(sizes)
+			// 10 small actions in chunks of 3, and the big document's 2 actions on their own
+			assert.Equal(t, []int{1, 1, 1, 3, 3, 3}, sizes)
+		})
+	}
+}
+
+func TestWriteDocsChunksKeepWritesInOrder(t *testing.T) {
+	fake := newFakeES(t, nil)
+	db, err := NewDB(&DBConfig{URL: fake.URL, Bulk: BulkConfig{MaxActions: 1, Concurrency: 3}}, []string{"index"}, logger.New("test"))
+	require.NoError(t, err)
+	// the first write of the document is slow to send
+	send := db.send
+	db.send = func(reqs []elastic.BulkableRequest) (*elastic.BulkResponse, error) {
+		if lines, _ := reqs[0].Source(); strings.Contains(lines[1], `"v":1`) {
+			time.Sleep(50 * time.Millisecond)
+		}
+		return send(reqs)
+	}
+
+	_, err = db.WriteDocs([]Doc{
+		{Op: OpTypeInsert, ID: "doc", Item: map[string]interface{}{"v": 1}},
+		{Op: OpTypeInsert, ID: "other", Item: map[string]interface{}{"v": 2}},
+		{Op: OpTypeUpdate, ID: "doc", Item: map[string]interface{}{"v": 3}},
+	})
+	require.NoError(t, err)
+
+	sources := []string{}
+	for _, bulk := range fake.Bulks() {
+		if bulk[0].ID() == "doc" {
+			sources = append(sources, string(bulk[0].Source))
+		}
+	}
+	assert.Equal(t, []string{`{"v":1}`, `{"v":3}`}, sources)
+}
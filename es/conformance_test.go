@@ -0,0 +1,449 @@
+package es
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/Clever/kayvee-go.v6/logger"
+)
+
+// implementation is a DB implementation run through the conformance suite
+type implementation struct {
+	// newFake starts a fake of the cluster the implementation writes to
+	newFake func(t *testing.T, respond func(action fakeBulkAction) (int, string)) *fakeES
+	open    func(config *DBConfig, indices []string) (DB, error)
+}
+
+var implementations = map[string]implementation{
+	APIv6: {
+		newFake: newFakeES,
+		open: func(config *DBConfig, indices []string) (DB, error) {
+			return NewDB(config, indices, logger.New("test"))
+		},
+	},
+	APITypeless: {
+		newFake: newTypelessFakeES,
+		open: func(config *DBConfig, indices []string) (DB, error) {
+			return NewTypelessDB(config, indices, logger.New("test"))
+		},
+	},
+}
+
+// TestConformance checks that every DB implementation writes Docs the same way
+func TestConformance(t *testing.T) {
+	for name, impl := range implementations {
+		impl := impl
+		t.Run(name, func(t *testing.T) {
+			t.Run("Open", func(t *testing.T) {
+				fake := impl.newFake(t, nil)
+				db, err := Open(&DBConfig{URL: fake.URL, API: name}, []string{"index"}, logger.New("test"))
+				require.NoError(t, err)
+				assert.IsType(t, mustOpen(t, impl, &DBConfig{URL: fake.URL}, []string{"index"}), db)
+			})
+			t.Run("Operations", func(t *testing.T) { testOperations(t, impl) })
+			t.Run("Results", func(t *testing.T) { testResults(t, impl) })
+			t.Run("Retries", func(t *testing.T) { testRetries(t, impl) })
+			t.Run("Indices", func(t *testing.T) { testIndices(t, impl) })
+			t.Run("Requests", func(t *testing.T) { testRequests(t, impl) })
+			t.Run("Create", func(t *testing.T) { testCreate(t, impl) })
+			t.Run("Bootstrap", func(t *testing.T) { testBootstrap(t, impl) })
+			t.Run("Aliases", func(t *testing.T) { testAliases(t, impl) })
+		})
+	}
+}
+
+func mustOpen(t *testing.T, impl implementation, config *DBConfig, indices []string) DB {
+	db, err := impl.open(config, indices)
+	require.NoError(t, err)
+	return db
+}
+
+func testOperations(t *testing.T, impl implementation) {
+	fake := impl.newFake(t, nil)
+	db := mustOpen(t, impl, &DBConfig{URL: fake.URL, SoftDeleteIndices: []string{"soft"}}, []string{"Index", "soft"})
+
+	item := map[string]interface{}{"a": "b"}
+	result, err := db.WriteDocs([]Doc{
+		{Op: OpTypeInsert, ID: "insert", Item: item},
+		{Op: OpTypeUpdate, ID: "update", Item: item},
+		{Op: OpTypeUpdate, ID: "partial", Item: item, Partial: true},
+		{Op: OpTypeDelete, ID: "delete", Item: item},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, result.Failed())
+
+	bulks := fake.Bulks()
+	require.Len(t, bulks, 1)
+	actions := []string{}
+	for _, action := range bulks[0] {
+		actions = append(actions, action.Op+" "+action.Index()+" "+action.ID())
+	}
+	assert.Equal(t, []string{
+		"index index insert", "index soft insert",
+		"index index update", "index soft update",
+		"update index partial", "update soft partial",
+		"delete index delete", "update soft delete",
+	}, actions)
+	assert.JSONEq(t, `{"a":"b"}`, string(bulks[0][0].Source))
+	assert.JSONEq(t, `{"doc":{"a":"b"},"doc_as_upsert":true}`, string(bulks[0][4].Source))
+	assert.JSONEq(t, `{"doc":{"a":"b","_deleted":true,"_expired":false},"doc_as_upsert":true}`, string(bulks[0][7].Source))
+}
+
+func testResults(t *testing.T, impl implementation) {
+	fake := impl.newFake(t, func(action fakeBulkAction) (int, string) {
+		switch action.ID() {
+		case "bad":
+			return 400, "mapper_parsing_exception"
+		case "missing":
+			return 404, ""
+		case "stale":
+			return 409, "version_conflict_engine_exception"
+		}
+		return 201, ""
+	})
+	db := mustOpen(t, impl, &DBConfig{URL: fake.URL, VersionType: VersionTypeExternal}, []string{"index"})
+
+	item := map[string]interface{}{"a": "b"}
+	result, err := db.WriteDocs([]Doc{
+		{Op: OpTypeInsert, ID: "good", Item: item},
+		{Op: OpTypeInsert, ID: "bad", Item: item},
+		{Op: OpTypeDelete, ID: "missing"},
+		{Op: OpTypeUpdate, ID: "stale", Item: item, Version: 1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, result.Failed())
+	assert.Equal(t, 1, result.Stale())
+	assert.Equal(t, DocResult{
+		ID: "bad",
+		Indices: []IndexResult{{
+			Index:     "index",
+			Status:    400,
+			ErrorType: "mapper_parsing_exception",
+			Reason:    "mapper_parsing_exception reason",
+		}},
+	}, result.Docs[1])
+}
+
+func testRetries(t *testing.T, impl implementation) {
+	attempts := map[string]int{}
+	fake := impl.newFake(t, func(action fakeBulkAction) (int, string) {
+		attempts[action.ID()]++
+		if action.ID() == "rejected" && attempts["rejected"] < 2 {
+			return 429, "es_rejected_execution_exception"
+		}
+		if action.ID() == "unavailable" {
+			return 503, "unavailable_shards_exception"
+		}
+		return 201, ""
+	})
+	db := mustOpen(t, impl, &DBConfig{
+		URL:   fake.URL,
+		Retry: RetryConfig{MaxRetries: 2, InitialBackoff: time.Millisecond},
+	}, []string{"index"})
+
+	item := map[string]interface{}{"a": "b"}
+	result, err := db.WriteDocs([]Doc{
+		{Op: OpTypeInsert, ID: "good", Item: item},
+		{Op: OpTypeInsert, ID: "rejected", Item: item},
+		{Op: OpTypeInsert, ID: "unavailable", Item: item},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, result.Failed())
+	assert.True(t, result.Docs[2].Indices[0].Retryable)
+	assert.Equal(t, map[string]int{"good": 1, "rejected": 2, "unavailable": 3}, attempts)
+}
+
+func testIndices(t *testing.T, impl implementation) {
+	fake := impl.newFake(t, nil)
+	db := mustOpen(t, impl, &DBConfig{URL: fake.URL}, []string{"events-{yyyy.MM}"})
+
+	july := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	item := map[string]interface{}{"a": "b"}
+	result, err := db.WriteDocs([]Doc{
+		{Op: OpTypeInsert, ID: "dated", Item: item, Timestamp: july},
+		{Op: OpTypeInsert, ID: "routed", Item: item, Timestamp: july, Indices: []string{"users", "all"}},
+		{Op: OpTypeInsert, ID: "undated", Item: item},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, result.Failed())
+	assert.Equal(t, "invalid_index_name_exception", result.Docs[2].Indices[0].ErrorType)
+
+	bulks := fake.Bulks()
+	require.Len(t, bulks, 1)
+	indices := []string{}
+	for _, action := range bulks[0] {
+		indices = append(indices, action.Index())
+	}
+	assert.Equal(t, []string{"events-2024.07", "users", "all"}, indices)
+}
+
+// requestTests are the bulk requests that every implementation builds from Docs
+var requestTests = []struct {
+	name    string
+	config  DBConfig
+	indices []string
+	respond func(action fakeBulkAction) (int, string)
+	docs    []Doc
+	// actions are those sent, as "op index id"
+	actions []string
+	// meta holds the versioning and routing metadata of each action, which has none of the rest
+	meta []map[string]interface{}
+	// sources holds the source of actions, by position
+	sources map[int]string
+	stale   int
+}{
+	{
+		name:    "versioning",
+		config:  DBConfig{VersionType: VersionTypeExternalGTE},
+		indices: []string{"index"},
+		respond: func(action fakeBulkAction) (int, string) {
+			if action.ID() == "stale" {
+				return 409, "version_conflict_engine_exception"
+			}
+			return 201, ""
+		},
+		docs: []Doc{
+			{Op: OpTypeInsert, ID: "versioned", Item: map[string]interface{}{"a": "b"}, Version: 2000},
+			{Op: OpTypeUpdate, ID: "versioned", Item: map[string]interface{}{"a": "c"}, Version: 3000},
+			{Op: OpTypeDelete, ID: "stale", Version: 1000},
+			{Op: OpTypeInsert, ID: "unversioned", Item: map[string]interface{}{"a": "b"}},
+		},
+		actions: []string{"index index versioned", "index index versioned", "delete index stale", "index index unversioned"},
+		meta: []map[string]interface{}{
+			{"version": 2000, "version_type": "external_gte"},
+			{"version": 3000, "version_type": "external_gte"},
+			{"version": 1000, "version_type": "external_gte"},
+			nil,
+		},
+		stale: 1,
+	},
+	{
+		name:    "routing",
+		indices: []string{"index"},
+		docs: []Doc{
+			{Op: OpTypeInsert, ID: "child", Item: map[string]interface{}{"a": "b"}, Routing: "org1"},
+			{Op: OpTypeDelete, ID: "child", Routing: "org1"},
+			{Op: OpTypeInsert, ID: "other", Item: map[string]interface{}{"a": "b"}},
+		},
+		actions: []string{"index index child", "delete index child", "index index other"},
+		meta:    []map[string]interface{}{{"routing": "org1"}, {"routing": "org1"}, nil},
+	},
+	{
+		name:    "partial updates",
+		indices: []string{"index"},
+		docs: []Doc{
+			{Op: OpTypeUpdate, ID: "partial", Item: map[string]interface{}{"a": "b", "removed": nil}, Partial: true, Routing: "r"},
+			{Op: OpTypeUpdate, ID: "full", Item: map[string]interface{}{"a": "b"}},
+		},
+		actions: []string{"update index partial", "index index full"},
+		meta:    []map[string]interface{}{{"routing": "r"}, nil},
+		sources: map[int]string{
+			0: `{"doc":{"a":"b","removed":null},"doc_as_upsert":true}`,
+			1: `{"a":"b"}`,
+		},
+	},
+	{
+		name:    "soft deletes",
+		config:  DBConfig{SoftDeleteIndices: []string{"soft-{yyyy}"}},
+		indices: []string{"soft-{yyyy}", "hard"},
+		docs: []Doc{
+			{
+				Op:        OpTypeDelete,
+				ID:        "expired",
+				Item:      map[string]interface{}{"a": "b"},
+				Timestamp: time.Date(2024, 7, 16, 22, 0, 0, 0, time.UTC),
+				DeletedAt: time.Date(2024, 7, 16, 22, 0, 0, 0, time.UTC),
+				Expired:   true,
+			},
+			// soft deleted in every index
+			{Op: OpTypeDelete, ID: "tombstone", Timestamp: time.Date(2024, 7, 16, 22, 0, 0, 0, time.UTC), SoftDelete: true},
+		},
+		actions: []string{"update soft-2024 expired", "delete hard expired", "update soft-2024 tombstone", "update hard tombstone"},
+		sources: map[int]string{
+			0: `{"doc":{"a":"b","_deleted":true,"_deletedAt":"2024-07-16T22:00:00Z","_expired":true},"doc_as_upsert":true}`,
+			3: `{"doc":{"_deleted":true,"_expired":false},"doc_as_upsert":true}`,
+		},
+	},
+}
+
+func testRequests(t *testing.T, impl implementation) {
+	for _, test := range requestTests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := impl.newFake(t, test.respond)
+			config := test.config
+			config.URL = fake.URL
+			db := mustOpen(t, impl, &config, test.indices)
+
+			result, err := db.WriteDocs(test.docs)
+			require.NoError(t, err)
+			assert.Empty(t, result.Failed())
+			assert.Equal(t, test.stale, result.Stale())
+
+			bulks := fake.Bulks()
+			require.Len(t, bulks, 1)
+			actions := []string{}
+			for _, action := range bulks[0] {
+				actions = append(actions, action.Op+" "+action.Index()+" "+action.ID())
+			}
+			require.Equal(t, test.actions, actions)
+			for i, meta := range test.meta {
+				for _, key := range []string{"version", "version_type", "routing"} {
+					if value, ok := meta[key]; ok {
+						assert.EqualValues(t, value, bulks[0][i].Meta[key], "%s of action %d", key, i)
+					} else {
+						assert.NotContains(t, bulks[0][i].Meta, key, "action %d", i)
+					}
+				}
+			}
+			for i, source := range test.sources {
+				assert.JSONEq(t, source, string(bulks[0][i].Source), "action %d", i)
+			}
+		})
+	}
+}
+
+func testCreate(t *testing.T, impl implementation) {
+	fake := impl.newFake(t, nil)
+	db := mustOpen(t, impl, &DBConfig{URL: fake.URL}, []string{"index"})
+
+	_, err := db.WriteDocs([]Doc{{Op: OpTypeUpdate, ID: "existing", Item: map[string]interface{}{"a": "newer"}}})
+	require.NoError(t, err)
+	result, err := db.WriteDocs([]Doc{
+		{Op: OpTypeInsert, ID: "existing", Item: map[string]interface{}{"a": "older"}, Create: true},
+		{Op: OpTypeInsert, ID: "new", Item: map[string]interface{}{"a": "older"}, Create: true},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, result.Failed())
+	assert.Equal(t, 1, result.Stale())
+
+	bulks := fake.Bulks()
+	require.Len(t, bulks, 2)
+	assert.Equal(t, "create", bulks[1][0].Op)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"existing": {"a": "newer"},
+		"new":      {"a": "older"},
+	}, fake.Docs("index"))
+}
+
+func testBootstrap(t *testing.T, impl implementation) {
+	fake := impl.newFake(t, nil)
+	// mappings are returned under the mapping type by Elasticsearch 6
+	typed := func(mappings map[string]interface{}) interface{} {
+		if fake.typeless {
+			return mappings
+		}
+		return map[string]interface{}{"default": mappings}
+	}
+	fake.CreateIndex("drifted", typed(map[string]interface{}{
+		"dynamic":    "false",
+		"properties": map[string]interface{}{"count": map[string]interface{}{"type": "text"}},
+	}))
+
+	declared := map[string]interface{}{
+		"dynamic": false,
+		"properties": map[string]interface{}{
+			"count": map[string]interface{}{"type": "long"},
+			"name":  map[string]interface{}{"type": "keyword"},
+		},
+	}
+	config := BootstrapConfig{
+		Template: Template{Settings: map[string]interface{}{"number_of_shards": 1}, Mappings: declared},
+		Indices:  []string{"Users", "drifted", "events-{yyyy.MM}"},
+	}
+	db := mustOpen(t, impl, &DBConfig{URL: fake.URL, Bootstrap: &config}, []string{"index"})
+
+	mappings := fake.Mappings()
+	assert.Contains(t, mappings, "users")
+	assert.NotContains(t, mappings, "index")
+	assert.True(t, sameValue(typed(declared), mappings["users"]))
+	templates := fake.Templates()
+	require.Contains(t, templates, "events-yyyy.mm")
+	assert.Equal(t, []interface{}{"events-*"}, templates["events-yyyy.mm"].(map[string]interface{})["index_patterns"])
+
+	// created indices and templates match the template, so only the existing index drifts
+	drift, err := db.(Bootstrapper).Bootstrap(config)
+	require.NoError(t, err)
+	assert.Equal(t, []Drift{
+		{Index: "drifted", Path: "properties.count.type", Declared: "long", Live: "text"},
+		{Index: "drifted", Path: "properties.name", Declared: map[string]interface{}{"type": "keyword"}},
+	}, drift)
+}
+
+func testAliases(t *testing.T, impl implementation) {
+	fake := impl.newFake(t, nil)
+	template := Template{Mappings: map[string]interface{}{"dynamic": false}}
+	db := mustOpen(t, impl, &DBConfig{
+		URL: fake.URL,
+		// refreshed on every write
+		Aliases:           &AliasConfig{},
+		Bootstrap:         &BootstrapConfig{Template: template},
+		SoftDeleteIndices: []string{"Users"},
+	}, []string{"Users"})
+	assert.Equal(t, map[string][]string{"users": {"users-v1"}}, fake.Aliases())
+
+	// deletes made while copying would be copied back
+	hardDeletes := mustOpen(t, impl, &DBConfig{URL: fake.URL, Aliases: &AliasConfig{}}, []string{"Users"})
+	_, err := hardDeletes.(Reindexer).Reindex("users", template)
+	assert.Error(t, err)
+
+	item := map[string]interface{}{"a": "b"}
+	_, err = db.WriteDocs([]Doc{{Op: OpTypeInsert, ID: "kept", Item: item}, {Op: OpTypeInsert, ID: "deleted", Item: item}})
+	require.NoError(t, err)
+
+	written := func() []string {
+		_, err := db.WriteDocs([]Doc{{Op: OpTypeInsert, ID: "id", Item: map[string]interface{}{"a": "b"}}})
+		require.NoError(t, err)
+		bulks := fake.Bulks()
+		indices := []string{}
+		for _, action := range bulks[len(bulks)-1] {
+			indices = append(indices, action.Index())
+		}
+		return indices
+	}
+	assert.Equal(t, []string{"users"}, written())
+
+	// a reindex that was interrupted while copying
+	fake.CreateIndex("users-v2", nil)
+	fake.AddAlias("users-reindex", "users-v2")
+	assert.Equal(t, []string{"users", "users-v2"}, written())
+
+	// a partial update would create a document in users-v2 that the reindex doesn't replace
+	result, err := db.WriteDocs([]Doc{{Op: OpTypeUpdate, ID: "id", Item: map[string]interface{}{"a": "c"}, Partial: true}})
+	require.NoError(t, err)
+	require.Len(t, result.Docs[0].Indices, 2)
+	assert.False(t, result.Docs[0].Indices[0].Failed())
+	assert.Equal(t, "users-v2", result.Docs[0].Indices[1].Index)
+	assert.Equal(t, reindexError, result.Docs[0].Indices[1].ErrorType)
+	bulks := fake.Bulks()
+	require.Len(t, bulks[len(bulks)-1], 1)
+	assert.Equal(t, "users", bulks[len(bulks)-1][0].Index())
+
+	// a document deleted while copying stays deleted
+	fake.OnReindex(func() {
+		_, err := db.WriteDocs([]Doc{{Op: OpTypeDelete, ID: "deleted", Item: item}})
+		require.NoError(t, err)
+	})
+	reindexed, err := db.(Reindexer).Reindex("Users", template)
+	require.NoError(t, err)
+	assert.Equal(t, ReindexResult{Alias: "users", From: "users-v1", To: "users-v2", Created: 1, Conflicts: 2}, reindexed)
+	docs := fake.Docs("users")
+	assert.Len(t, docs, 3)
+	assert.Equal(t, item, docs["kept"])
+	assert.Equal(t, true, docs["deleted"]["_deleted"])
+	fake.OnReindex(nil)
+	assert.Equal(t, []map[string]interface{}{{
+		"conflicts": "proceed",
+		"source":    map[string]interface{}{"index": "users-v1"},
+		"dest":      map[string]interface{}{"index": "users-v2", "op_type": "create"},
+	}}, fake.Reindexes())
+	assert.Equal(t, []string{"users-v2"}, fake.Aliases()["users"])
+	assert.Equal(t, []string{"users"}, written())
+
+	reindexed, err = db.(Reindexer).Reindex("users", template)
+	require.NoError(t, err)
+	assert.Equal(t, "users-v3", reindexed.To)
+	assert.Contains(t, fake.Mappings(), "users-v3")
+	assert.Equal(t, []string{"users-v3"}, fake.Aliases()["users"])
+}
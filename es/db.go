@@ -3,7 +3,10 @@ package es
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/Clever/kayvee-go.v6/logger"
 	elastic "gopkg.in/olivere/elastic.v6"
@@ -38,112 +41,482 @@ type Doc struct {
 	Op   OpType
 	ID   string
 	Item interface{}
+	// Version orders writes to the same document when DBConfig.VersionType is set.
+	// It is derived from the DynamoDB stream record, and is ignored if zero.
+	Version int64 `json:",omitempty"`
+	// Timestamp resolves date patterns in index names, such as "events-{yyyy.MM}"
+	Timestamp time.Time
+	// Indices overrides the indices the DB was created with, for documents routed to
+	// their own indices. Supports the same date patterns.
+	Indices []string `json:",omitempty"`
+	// Routing overrides the default routing by ID, picking the shard of the document.
+	// Deletes must be routed the same way as the document was indexed.
+	Routing string `json:",omitempty"`
+	// Partial marks an update whose Item only holds the changed attributes. It is written with
+	// the update API, creating the document from them if it doesn't exist.
+	Partial bool `json:",omitempty"`
+	// DeletedAt is when a deleted item was removed, recorded on soft deleted documents
+	DeletedAt time.Time `json:",omitempty"`
+	// Expired marks a delete made by DynamoDB's TTL expiry, rather than by the application
+	Expired bool `json:",omitempty"`
+	// SoftDelete marks a deleted document as deleted in every index, as if they were all SoftDeleteIndices
+	SoftDelete bool `json:",omitempty"`
+	// Create only writes an inserted or updated document if it doesn't exist, such as an item loaded
+	// from a snapshot that may be older than the document. A document that exists is reported as Stale.
+	// Versioned documents are written as usual, as their version decides instead.
+	Create bool `json:",omitempty"`
 }
 
+// Version types supported for external versioning.
+// See https://www.elastic.co/guide/en/elasticsearch/reference/6.3/docs-index_.html#_version_types
+const (
+	// VersionTypeExternal only accepts a write if its version is greater than the stored one
+	VersionTypeExternal = "external"
+	// VersionTypeExternalGTE also accepts a write with the same version as the stored one
+	VersionTypeExternalGTE = "external_gte"
+)
+
+// APIs of the clusters written to
+const (
+	// APIv6 writes documents with the "default" mapping type, for Elasticsearch 6
+	APIv6 = "v6"
+	// APITypeless writes documents without mapping types, for Elasticsearch 7 and 8 and OpenSearch
+	APITypeless = "typeless"
+)
+
 // DBConfig specifies how the client should connect to ElasticSearch
 type DBConfig struct {
 	URL string
+	// API is v6 or typeless. Defaults to v6.
+	API  string
+	Auth AuthConfig
+	TLS  TLSConfig
+	// Retry specifies how documents that fail with a retryable error are re-submitted
+	Retry RetryConfig
+	// Bulk specifies how documents are split into bulk requests
+	Bulk BulkConfig
+	// Limits the size of each document, before it is added to a bulk request
+	Limits LimitsConfig
+	// VersionType enables external versioning using Doc.Version, so that writes older than the
+	// indexed document are rejected by Elasticsearch. Disabled if empty.
+	VersionType string
+	// SoftDeleteIndices lists the indices, as configured, where deletes mark the document as
+	// deleted instead of removing it. The document keeps the Doc's Item, which should be the
+	// item's last known image.
+	SoftDeleteIndices []string
+	// Bootstrap creates the indices and index templates missing from the cluster when the DB is
+	// created, and logs where their mappings drift from the template. Disabled if nil.
+	Bootstrap *BootstrapConfig
+	// Aliases writes through write aliases named as the indices, which Bootstrap creates, also
+	// writing to the indices being reindexed into for them. Disabled if nil.
+	Aliases *AliasConfig
 }
 
 // DB allows for the writing Doc's to a backend
 type DB interface {
-	WriteDocs([]Doc) error
+	WriteDocs([]Doc) (WriteResult, error)
 }
 
-// Elasticsearch exposes functionality to read and write from ElasticSearch
+// Open creates the DB for the config's API
+func Open(config *DBConfig, indices []string, lg logger.KayveeLogger) (DB, error) {
+	switch config.API {
+	case "", APIv6:
+		return NewDB(config, indices, lg)
+	case APITypeless:
+		return NewTypelessDB(config, indices, lg)
+	default:
+		return nil, fmt.Errorf("unsupported API %q", config.API)
+	}
+}
+
+// Elasticsearch exposes functionality to read and write from ElasticSearch 6
 type Elasticsearch struct {
-	client  *elastic.Client
+	client *elastic.Client
+	writer
+}
+
+// writer writes Doc's with bulk requests, however they are sent
+type writer struct {
 	config  *DBConfig
 	indices []string
 	lg      logger.KayveeLogger
+	// mappingType of the documents written, empty for typeless clusters
+	mappingType string
+	// send sends a bulk request, returning an error if it could not be sent or was rejected as a whole
+	send func(reqs []elastic.BulkableRequest) (*elastic.BulkResponse, error)
+	// perform sends any other request, JSON encoding body if it isn't nil. A missing index or
+	// template is reported by its status, while other failures are returned as errors.
+	perform func(method, path string, body interface{}) (status int, resp []byte, err error)
+	// sleep waits between retries. Overridden in tests.
+	sleep func(time.Duration)
+
+	aliasMu sync.Mutex
+	aliases aliasState
 }
 
 // NewDB creates a new DB instance
 func NewDB(config *DBConfig, indices []string, lg logger.KayveeLogger) (*Elasticsearch, error) {
-	client, err := elastic.NewClient(
+	httpClient, err := httpClient(config)
+	if err != nil {
+		return nil, err
+	}
+	options := []elastic.ClientOptionFunc{
 		elastic.SetURL(config.URL),
+		elastic.SetHttpClient(httpClient),
 		elastic.SetSniff(false),
 		elastic.SetRetrier(elastic.NewBackoffRetrier(elastic.NewSimpleBackoff(1000, 2000, 4000))),
-	)
+	}
+	if config.Auth.Username != "" {
+		options = append(options, elastic.SetBasicAuth(config.Auth.Username, config.Auth.Password))
+	}
+	client, err := elastic.NewClient(options...)
 	if err != nil {
 		return nil, fmt.Errorf("Could not connect to cluster: %s", err)
 	}
 
-	return &Elasticsearch{
-		client:  client,
-		config:  config,
-		indices: indices,
-		lg:      lg,
-	}, nil
+	db := &Elasticsearch{
+		client: client,
+		writer: writer{
+			config:      config,
+			indices:     indices,
+			lg:          lg,
+			mappingType: "default",
+			send: func(reqs []elastic.BulkableRequest) (*elastic.BulkResponse, error) {
+				return client.Bulk().Add(reqs...).Do(context.Background())
+			},
+			perform: func(method, path string, body interface{}) (int, []byte, error) {
+				resp, err := client.PerformRequest(context.Background(), elastic.PerformRequestOptions{
+					Method:       method,
+					Path:         path,
+					Body:         body,
+					IgnoreErrors: []int{http.StatusNotFound},
+				})
+				if err != nil {
+					return 0, nil, err
+				}
+				return resp.StatusCode, resp.Body, nil
+			},
+			sleep: time.Sleep,
+		},
+	}
+	if err := db.bootstrap(); err != nil {
+		return nil, err
+	}
+	return db, nil
 }
 
-// WriteDocs implements the writing Doc's to elasticsearch as a batch
-func (db *Elasticsearch) WriteDocs(docs []Doc) error {
-	bulkRequest := db.client.Bulk()
+// WriteDocs implements the writing Doc's to elasticsearch as a batch.
+// An error is returned only if the batch as a whole could not be written;
+// the outcome of each document is reported in the WriteResult.
+func (db *writer) WriteDocs(docs []Doc) (WriteResult, error) {
+	reindexing := map[string][]string{}
+	if db.config.Aliases != nil {
+		var err error
+		if reindexing, err = db.reindexing(); err != nil {
+			return WriteResult{}, err
+		}
+	}
 
-	for _, doc := range docs {
-		for _, index := range db.indices {
-			req := toESRequest(doc, index)
-			// TODO: handle nil (error) cases better. For now let's just keep going
-			if req != nil {
-				bulkRequest.Add(req)
+	actions := []bulkAction{}
+	for i, doc := range docs {
+		doc, err := db.limit(doc)
+		if err != nil {
+			for _, pattern := range db.indicesFor(doc) {
+				actions = append(actions, bulkAction{doc: i, index: pattern, err: err, errorType: limitError})
+			}
+			continue
+		}
+		for _, pattern := range db.indicesFor(doc) {
+			index, err := IndexName(pattern, doc.Timestamp)
+			if err != nil {
+				actions = append(actions, bulkAction{doc: i, index: pattern, err: err, errorType: "invalid_index_name_exception"})
+				continue
+			}
+			// indices being reindexed into are written to as well, so they don't miss changes
+			for _, target := range append([]string{index}, reindexing[strings.ToLower(index)]...) {
+				if doc.Partial && target != index {
+					err := fmt.Errorf("partial updates can't be written to %s while it's being reindexed into", target)
+					actions = append(actions, bulkAction{doc: i, index: target, err: err, errorType: reindexError})
+					continue
+				}
+				req := toESRequest(doc, target, requestOptions{
+					mappingType: db.mappingType,
+					versionType: db.config.VersionType,
+					softDelete:  doc.SoftDelete || db.softDeletes(pattern),
+				})
+				// TODO: handle nil (error) cases better. For now let's just keep going
+				if req == nil {
+					continue
+				}
+				size, err := requestSize(req)
+				if err != nil {
+					actions = append(actions, bulkAction{doc: i, index: target, err: err, errorType: "mapper_parsing_exception"})
+					continue
+				}
+				actions = append(actions, bulkAction{doc: i, index: target, id: doc.ID, req: req, size: size})
 			}
 		}
 	}
 
-	if bulkRequest.NumberOfActions() == 0 {
-		return nil
+	results, err := db.bulk(actions)
+	if err != nil {
+		return WriteResult{}, err
 	}
 
-	resp, err := bulkRequest.Do(context.Background())
-	if err != nil {
-		db.lg.ErrorD("write-failed", logger.M{
-			"error-type":   "UNKNOWN",
-			"error-reason": err.Error(),
+	result := WriteResult{Docs: make([]DocResult, len(docs))}
+	for i, doc := range docs {
+		result.Docs[i].ID = doc.ID
+	}
+	for i, action := range actions {
+		docResult := &result.Docs[action.doc]
+		docResult.Indices = append(docResult.Indices, results[i])
+		if results[i].Failed() {
+			db.lg.ErrorD("document-write-failed", logger.M{
+				"error-type":   results[i].ErrorType,
+				"doc-id":       docResult.ID,
+				"index":        results[i].Index,
+				"status":       results[i].Status,
+				"error-reason": results[i].Reason,
+				"retryable":    results[i].Retryable,
+			})
+		}
+	}
+	if stale := result.Stale(); stale > 0 {
+		db.lg.InfoD("stale-writes-rejected", logger.M{
+			"count": stale,
 		})
-		return err
 	}
 
-	if !resp.Errors {
-		return nil
+	return result, nil
+}
+
+// limit applies the document limits to the Doc's Item. Deletes are never rejected, but
+// lose their item instead.
+func (db *writer) limit(doc Doc) (Doc, error) {
+	item, ok := doc.Item.(map[string]interface{})
+	if !ok || !db.config.Limits.enabled() {
+		return doc, nil
+	}
+	limited, err := db.config.Limits.apply(item)
+	if err != nil {
+		if doc.Op == OpTypeDelete {
+			doc.Item = nil
+			return doc, nil
+		}
+		return doc, err
+	}
+	doc.Item = limited
+	return doc, nil
+}
+
+// indicesFor returns the index patterns a Doc is written to
+func (db *writer) indicesFor(doc Doc) []string {
+	if len(doc.Indices) > 0 {
+		return doc.Indices
 	}
+	return db.indices
+}
 
-	// log all errors
-	for _, failed := range resp.Failed() {
-		if failed.Error != nil {
-			db.lg.ErrorD("document-write-failed", logger.M{
-				"error-type":   failed.Error.Type,
-				"doc-id":       failed.Id,
-				"error-reason": failed.Error.Reason,
+// softDeletes returns true if deletes mark documents as deleted in the index with the given pattern
+func (db *writer) softDeletes(pattern string) bool {
+	for _, index := range db.config.SoftDeleteIndices {
+		if index == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// bulk sends the actions as bulk requests and returns the result of each.
+// If any bulk request could not be sent an error is returned, and the batch should be retried whole.
+// Actions that fail with a retryable error are re-submitted with backoff, along with every later
+// action writing the same document, while permanent failures are returned immediately.
+func (db *writer) bulk(actions []bulkAction) ([]IndexResult, error) {
+	results := make([]IndexResult, len(actions))
+	pending := []int{}
+	for i, action := range actions {
+		if action.err != nil {
+			// the request couldn't be built, so there is nothing to send
+			results[i] = IndexResult{
+				Index:     action.index,
+				ErrorType: action.errorType,
+				Reason:    action.err.Error(),
+			}
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	for retry := 0; len(pending) > 0; retry++ {
+		if retry > 0 {
+			db.lg.InfoD("bulk-retry", logger.M{
+				"retry":   retry,
+				"actions": len(pending),
 			})
-		} else {
-			db.lg.ErrorD("document-write-failed", logger.M{
+			db.sleep(db.config.Retry.backoff(retry))
+		}
+
+		chunks := db.config.Bulk.chunks(pending, actions)
+		responses, err := db.sendChunks(chunks, actions)
+		if err != nil {
+			db.lg.ErrorD("write-failed", logger.M{
 				"error-type":   "UNKNOWN",
-				"doc-id":       failed.Id,
-				"error-reason": "UNKNOWN",
+				"error-reason": err.Error(),
 			})
+			return nil, err
 		}
+
+		// items are returned in the same order the requests were added
+		for c, chunk := range chunks {
+			for j, item := range responses[c].Items {
+				if j >= len(chunk) {
+					break
+				}
+				i := chunk[j]
+				for op, bulkItem := range item {
+					results[i] = toIndexResult(op, actions[i].index, bulkItem)
+				}
+			}
+		}
+
+		// every later write of a document that is retried is retried after it, so that an older
+		// write never replaces a newer one
+		retrying := map[string]bool{}
+		retryable := []int{}
+		for _, i := range pending {
+			key := actions[i].key()
+			if retrying[key] || (results[i].Retryable && retry < db.config.Retry.MaxRetries) {
+				retrying[key] = true
+				retryable = append(retryable, i)
+			}
+		}
+		pending = retryable
+	}
+
+	return results, nil
+}
+
+// bulkAction identifies the Doc and index a bulk request was built from
+type bulkAction struct {
+	doc   int
+	index string
+	// id of the document written
+	id  string
+	req elastic.BulkableRequest
+	// size of the request in a bulk request body
+	size int
+	// err is set instead of req if the request could not be built, with the type of error it is reported as
+	err       error
+	errorType string
+}
+
+// key identifies the document an action writes, so that writes of the same document are kept in order
+func (a bulkAction) key() string {
+	return strings.ToLower(a.index) + "/" + a.id
+}
+
+// toIndexResult converts a bulk response item for the given operation to an IndexResult
+func toIndexResult(op, index string, item *elastic.BulkResponseItem) IndexResult {
+	result := IndexResult{Index: index, Status: item.Status}
+	if item.Index != "" {
+		result.Index = item.Index
+	}
+	// deleting a document that was never indexed leaves the index in the desired state
+	if op == "delete" && item.Status == http.StatusNotFound {
+		result.Status = http.StatusOK
+	}
+	// the index already holds a newer version of the document
+	if item.Status == http.StatusConflict && item.Error != nil && item.Error.Type == "version_conflict_engine_exception" {
+		result.Stale = true
+	}
+	if !result.Failed() {
+		return result
 	}
+	if item.Error != nil {
+		result.ErrorType = item.Error.Type
+		result.Reason = item.Error.Reason
+	} else {
+		result.ErrorType = "UNKNOWN"
+		result.Reason = "UNKNOWN"
+	}
+	result.Retryable = isRetryable(result.Status, result.ErrorType)
+	return result
+}
 
-	return fmt.Errorf("errors-during-write")
+// requestOptions specifies how a Doc is written to an index
+type requestOptions struct {
+	// mappingType of the document, empty for typeless clusters
+	mappingType string
+	versionType string
+	// softDelete marks deleted documents as deleted instead of removing them
+	softDelete bool
 }
 
-func toESRequest(doc Doc, rawIndexName string) elastic.BulkableRequest {
+func toESRequest(doc Doc, rawIndexName string, opts requestOptions) elastic.BulkableRequest {
 	// make sure we don't have invalid indexes
 	index := strings.ToLower(rawIndexName)
 	if index == "" {
 		index = "unknown"
 	}
+	versioned := opts.versionType != "" && doc.Version > 0
 
 	switch doc.Op {
 	case OpTypeInsert:
 		fallthrough
 	case OpTypeUpdate:
-		return elastic.NewBulkIndexRequest().Index(index).Type("default").Id(doc.ID).Doc(doc.Item)
+		if doc.Partial {
+			// the update API doesn't support external versions
+			req := elastic.NewBulkUpdateRequest().Index(index).Type(opts.mappingType).Id(doc.ID).Doc(doc.Item).DocAsUpsert(true)
+			if doc.Routing != "" {
+				req = req.Routing(doc.Routing)
+			}
+			return req
+		}
+		req := elastic.NewBulkIndexRequest().Index(index).Type(opts.mappingType).Id(doc.ID).Doc(doc.Item)
+		if versioned {
+			req = req.VersionType(opts.versionType).Version(doc.Version)
+		} else if doc.Create {
+			req = req.OpType("create")
+		}
+		if doc.Routing != "" {
+			req = req.Routing(doc.Routing)
+		}
+		return req
 	case OpTypeDelete:
-		return elastic.NewBulkDeleteRequest().Index(index).Type("default").Id(doc.ID)
+		if opts.softDelete {
+			// updated rather than replaced, so fields other pipelines add to the document are kept
+			req := elastic.NewBulkUpdateRequest().Index(index).Type(opts.mappingType).Id(doc.ID).Doc(toTombstone(doc)).DocAsUpsert(true)
+			if doc.Routing != "" {
+				req = req.Routing(doc.Routing)
+			}
+			return req
+		}
+		req := elastic.NewBulkDeleteRequest().Index(index).Type(opts.mappingType).Id(doc.ID)
+		if versioned {
+			req = req.VersionType(opts.versionType).Version(doc.Version)
+		}
+		if doc.Routing != "" {
+			req = req.Routing(doc.Routing)
+		}
+		return req
 	default:
 		fmt.Printf("INVALID DOC TYPE %s; %s", doc.Op, doc.ID)
 		return nil
 	}
 }
+
+// toTombstone returns the Item of a deleted Doc, marked as deleted
+func toTombstone(doc Doc) map[string]interface{} {
+	tombstone := map[string]interface{}{}
+	if item, ok := doc.Item.(map[string]interface{}); ok {
+		for k, v := range item {
+			tombstone[k] = v
+		}
+	}
+	tombstone["_deleted"] = true
+	tombstone["_expired"] = doc.Expired
+	if !doc.DeletedAt.IsZero() {
+		tombstone["_deletedAt"] = doc.DeletedAt
+	}
+	return tombstone
+}
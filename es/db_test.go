@@ -41,8 +41,9 @@ func TestWriteDocs(t *testing.T) {
 	setupIndices(t, db.client, indices)
 
 	for _, test := range tests {
-		err = db.WriteDocs(test.docs)
+		result, err := db.WriteDocs(test.docs)
 		assert.NoError(t, err)
+		assert.Empty(t, result.Failed())
 	}
 
 	deleteIndices(db.client, indices)
@@ -64,8 +65,9 @@ func TestWriteDocsToMultipleIndices(t *testing.T) {
 
 	setupIndices(t, db.client, indices)
 
-	err = db.WriteDocs([]Doc{testDoc})
+	result, err := db.WriteDocs([]Doc{testDoc})
 	assert.NoError(t, err)
+	assert.Empty(t, result.Failed())
 
 	for _, index := range indices {
 		assertIndexHasDoc(t, db.client, index, testDoc.ID)
@@ -85,8 +87,9 @@ func TestWriteDocsComplexBatch(t *testing.T) {
 
 	setupIndices(t, db.client, indices)
 
-	err = db.WriteDocs(*docs)
+	result, err := db.WriteDocs(*docs)
 	assert.NoError(t, err)
+	assert.Empty(t, result.Failed())
 
 	deleteIndices(db.client, indices)
 }
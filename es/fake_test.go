@@ -0,0 +1,429 @@
+package es
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeBulkAction is a single action parsed from a bulk request sent to a fakeES
+type fakeBulkAction struct {
+	Op     string
+	Meta   map[string]interface{}
+	Source json.RawMessage
+}
+
+// Index returns the index the action targets
+func (a fakeBulkAction) Index() string {
+	index, _ := a.Meta["_index"].(string)
+	return index
+}
+
+// ID returns the document ID the action targets
+func (a fakeBulkAction) ID() string {
+	id, _ := a.Meta["_id"].(string)
+	return id
+}
+
+// fakeES is an httptest server implementing enough of the Elasticsearch API
+// to create a client and send bulk requests, so tests don't need a running cluster.
+type fakeES struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	// respond returns the status and error type of each bulk action. Every action succeeds if nil.
+	respond func(action fakeBulkAction) (int, string)
+	// bulks holds the actions of each bulk request received
+	bulks [][]fakeBulkAction
+	// headers holds the headers of every request received
+	headers []http.Header
+	// typeless rejects actions with a mapping type, as Elasticsearch 8 and OpenSearch 2 do.
+	// Otherwise actions without one are rejected, as Elasticsearch 6 does.
+	typeless bool
+	// mappings holds the mappings of each index created, as sent
+	mappings map[string]interface{}
+	// templates holds the body of each index template created
+	templates map[string]interface{}
+	// aliases holds the indices of each alias
+	aliases map[string][]string
+	// reindexes holds the body of each reindex request received
+	reindexes []map[string]interface{}
+	// docs holds the source of each document written, by concrete index and ID
+	docs map[string]map[string]map[string]interface{}
+	// onReindex is called once a reindex has read the documents it copies, before it writes them
+	onReindex func()
+}
+
+// newFakeES starts a fakeES that is closed when the test finishes.
+func newFakeES(t *testing.T, respond func(action fakeBulkAction) (int, string)) *fakeES {
+	return startFakeES(t, &fakeES{respond: respond})
+}
+
+// newTypelessFakeES starts a fakeES of a cluster without mapping types
+func newTypelessFakeES(t *testing.T, respond func(action fakeBulkAction) (int, string)) *fakeES {
+	return startFakeES(t, &fakeES{respond: respond, typeless: true})
+}
+
+func startFakeES(t *testing.T, f *fakeES) *fakeES {
+	f.mappings, f.templates, f.aliases = map[string]interface{}{}, map[string]interface{}{}, map[string][]string{}
+	f.docs = map[string]map[string]map[string]interface{}{}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// Bulks returns the actions of each bulk request received so far
+func (f *fakeES) Bulks() [][]fakeBulkAction {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]fakeBulkAction{}, f.bulks...)
+}
+
+// CreateIndex creates an index with the mappings, as the cluster returns them
+func (f *fakeES) CreateIndex(index string, mappings interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mappings[index] = mappings
+}
+
+// Mappings returns the mappings of each index created so far
+func (f *fakeES) Mappings() map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	mappings := map[string]interface{}{}
+	for k, v := range f.mappings {
+		mappings[k] = v
+	}
+	return mappings
+}
+
+// AddAlias adds an index to an alias
+func (f *fakeES) AddAlias(alias, index string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.aliases[alias] = append(f.aliases[alias], index)
+}
+
+// Aliases returns the indices of each alias
+func (f *fakeES) Aliases() map[string][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	aliases := map[string][]string{}
+	for k, v := range f.aliases {
+		aliases[k] = append([]string{}, v...)
+	}
+	return aliases
+}
+
+// Docs returns the source of each document of an index, by ID
+func (f *fakeES) Docs(index string) map[string]map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	docs := map[string]map[string]interface{}{}
+	for id, doc := range f.docs[f.resolve(index)] {
+		docs[id] = doc
+	}
+	return docs
+}
+
+// OnReindex sets a function called while a reindex copies documents, after it has read them
+func (f *fakeES) OnReindex(fn func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.onReindex = fn
+}
+
+// resolve returns the index an alias writes to, or the index itself
+func (f *fakeES) resolve(index string) string {
+	if indices := f.aliases[index]; len(indices) > 0 {
+		return indices[0]
+	}
+	return index
+}
+
+// store applies a successful bulk action to the documents, returning its status and error type
+func (f *fakeES) store(action fakeBulkAction, status int) (int, string) {
+	index := f.resolve(action.Index())
+	if f.docs == nil {
+		f.docs = map[string]map[string]map[string]interface{}{}
+	}
+	if f.docs[index] == nil {
+		f.docs[index] = map[string]map[string]interface{}{}
+	}
+	docs := f.docs[index]
+	switch action.Op {
+	case "create":
+		if _, ok := docs[action.ID()]; ok {
+			return http.StatusConflict, "version_conflict_engine_exception"
+		}
+		fallthrough
+	case "index":
+		source := map[string]interface{}{}
+		json.Unmarshal(action.Source, &source)
+		docs[action.ID()] = source
+	case "update":
+		update := struct {
+			Doc map[string]interface{} `json:"doc"`
+		}{}
+		json.Unmarshal(action.Source, &update)
+		doc := docs[action.ID()]
+		if doc == nil {
+			doc = map[string]interface{}{}
+		}
+		for k, v := range update.Doc {
+			doc[k] = v
+		}
+		docs[action.ID()] = doc
+	case "delete":
+		if _, ok := docs[action.ID()]; !ok {
+			return http.StatusNotFound, ""
+		}
+		delete(docs, action.ID())
+	}
+	return status, ""
+}
+
+// Reindexes returns the body of each reindex request received so far
+func (f *fakeES) Reindexes() []map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]map[string]interface{}{}, f.reindexes...)
+}
+
+// Templates returns the body of each index template created so far
+func (f *fakeES) Templates() map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	templates := map[string]interface{}{}
+	for k, v := range f.templates {
+		templates[k] = v
+	}
+	return templates
+}
+
+// serveIndices serves the index and index template APIs, returning false for other requests
+func (f *fakeES) serveIndices(w http.ResponseWriter, r *http.Request) bool {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) > 2 || parts[0] == "" || strings.HasSuffix(r.URL.Path, "/_bulk") {
+		return false
+	}
+	templatesAPI := "_template"
+	if f.typeless {
+		templatesAPI = "_index_template"
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case len(parts) == 2 && parts[0] == templatesAPI:
+		name := parts[1]
+		if r.Method == http.MethodPut {
+			var body interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			f.templates[name] = body
+			fmt.Fprint(w, `{"acknowledged":true}`)
+			return true
+		}
+		body, ok := f.templates[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{}`)
+			return true
+		}
+		if f.typeless {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"index_templates": []interface{}{map[string]interface{}{"name": name, "index_template": body}},
+			})
+		} else {
+			json.NewEncoder(w).Encode(map[string]interface{}{name: body})
+		}
+	case len(parts) == 2 && parts[0] == "_alias":
+		indices := map[string]map[string]interface{}{}
+		for alias, names := range f.aliases {
+			for _, index := range names {
+				if indices[index] == nil {
+					indices[index] = map[string]interface{}{}
+				}
+				indices[index][alias] = map[string]interface{}{}
+			}
+		}
+		resp := map[string]interface{}{}
+		for index, aliases := range indices {
+			resp[index] = map[string]interface{}{"aliases": aliases}
+		}
+		json.NewEncoder(w).Encode(resp)
+	case len(parts) == 1 && parts[0] == "_aliases":
+		body := struct {
+			Actions []map[string]struct{ Index, Alias string } `json:"actions"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&body)
+		for _, action := range body.Actions {
+			for op, a := range action {
+				indices := []string{}
+				for _, index := range f.aliases[a.Alias] {
+					if index != a.Index {
+						indices = append(indices, index)
+					}
+				}
+				if op == "add" {
+					indices = append(indices, a.Index)
+				}
+				f.aliases[a.Alias] = indices
+			}
+		}
+		fmt.Fprint(w, `{"acknowledged":true}`)
+	case len(parts) == 1 && parts[0] == "_reindex":
+		body := struct {
+			Source struct{ Index string } `json:"source"`
+			Dest   struct{ Index string } `json:"dest"`
+		}{}
+		raw := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&raw)
+		encoded, _ := json.Marshal(raw)
+		json.Unmarshal(encoded, &body)
+		f.reindexes = append(f.reindexes, raw)
+
+		// copied from a snapshot of the source, as _reindex does
+		snapshot := map[string]map[string]interface{}{}
+		for id, doc := range f.docs[body.Source.Index] {
+			snapshot[id] = doc
+		}
+		if onReindex := f.onReindex; onReindex != nil {
+			f.mu.Unlock()
+			onReindex()
+			f.mu.Lock()
+		}
+		// documents aren't versioned, so any already in the destination conflict
+		if f.docs[body.Dest.Index] == nil {
+			f.docs[body.Dest.Index] = map[string]map[string]interface{}{}
+		}
+		created, conflicts := 0, 0
+		for id, doc := range snapshot {
+			if _, ok := f.docs[body.Dest.Index][id]; ok {
+				conflicts++
+				continue
+			}
+			f.docs[body.Dest.Index][id] = doc
+			created++
+		}
+		fmt.Fprintf(w, `{"created":%d,"version_conflicts":%d,"failures":[]}`, created, conflicts)
+	case len(parts) == 2 && parts[1] == "_mapping":
+		index := parts[0]
+		if indices := f.aliases[index]; len(indices) > 0 {
+			index = indices[0]
+		}
+		mappings, ok := f.mappings[index]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"error":{"type":"index_not_found_exception"},"status":404}`)
+			return true
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{index: map[string]interface{}{"mappings": mappings}})
+	case len(parts) == 1 && r.Method == http.MethodPut:
+		body := struct {
+			Mappings interface{}            `json:"mappings"`
+			Aliases  map[string]interface{} `json:"aliases"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.mappings[parts[0]] = body.Mappings
+		for alias := range body.Aliases {
+			f.aliases[alias] = append(f.aliases[alias], parts[0])
+		}
+		fmt.Fprint(w, `{"acknowledged":true}`)
+	default:
+		return false
+	}
+	return true
+}
+
+// Headers returns the headers of every request received so far
+func (f *fakeES) Headers() []http.Header {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]http.Header{}, f.headers...)
+}
+
+func (f *fakeES) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.headers = append(f.headers, r.Header.Clone())
+	f.mu.Unlock()
+
+	if f.serveIndices(w, r) {
+		return
+	}
+	if !strings.HasSuffix(r.URL.Path, "/_bulk") {
+		w.Header().Set("Content-Type", "application/json")
+		version := "6.3.2"
+		if f.typeless {
+			version = "8.11.0"
+		}
+		fmt.Fprintf(w, `{"version":{"number":%q},"tagline":"You Know, for Search"}`, version)
+		return
+	}
+
+	actions := []fakeBulkAction{}
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := map[string]map[string]interface{}{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for op, meta := range line {
+			action := fakeBulkAction{Op: op, Meta: meta}
+			if op != "delete" && scanner.Scan() {
+				action.Source = append(json.RawMessage{}, scanner.Bytes()...)
+			}
+			actions = append(actions, action)
+		}
+	}
+
+	f.mu.Lock()
+	f.bulks = append(f.bulks, actions)
+	respond := f.respond
+	f.mu.Unlock()
+
+	hasErrors := false
+	items := []map[string]interface{}{}
+	for _, action := range actions {
+		status, errorType := http.StatusOK, ""
+		_, typed := action.Meta["_type"]
+		switch {
+		case f.typeless && typed:
+			status, errorType = http.StatusBadRequest, "illegal_argument_exception"
+		case !f.typeless && !typed:
+			status, errorType = http.StatusBadRequest, "action_request_validation_exception"
+		case respond != nil:
+			status, errorType = respond(action)
+		}
+		if errorType == "" {
+			f.mu.Lock()
+			status, errorType = f.store(action, status)
+			f.mu.Unlock()
+		}
+		item := map[string]interface{}{
+			"_index": action.Index(),
+			"_id":    action.ID(),
+			"status": status,
+		}
+		if errorType != "" {
+			hasErrors = true
+			item["error"] = map[string]interface{}{"type": errorType, "reason": errorType + " reason"}
+		}
+		items = append(items, map[string]interface{}{action.Op: item})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"took":   1,
+		"errors": hasErrors,
+		"items":  items,
+	})
+}
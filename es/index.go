@@ -0,0 +1,75 @@
+package es
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dateTokens maps the date tokens supported in index name patterns to Go time layouts
+var dateTokens = []struct {
+	token  string
+	layout string
+}{
+	{"yyyy", "2006"},
+	{"yy", "06"},
+	{"MM", "01"},
+	{"dd", "02"},
+	{"HH", "15"},
+}
+
+// IndexName resolves an index name pattern for a document timestamp. Dates in braces, such as
+// "events-{yyyy.MM}", are formatted with the timestamp in UTC using the yyyy, yy, MM, dd and HH tokens.
+// Names without braces are returned unchanged.
+func IndexName(pattern string, timestamp time.Time) (string, error) {
+	if !strings.Contains(pattern, "{") {
+		return pattern, nil
+	}
+	if timestamp.IsZero() {
+		return "", fmt.Errorf("index %s needs a document timestamp", pattern)
+	}
+
+	name := &strings.Builder{}
+	rest := pattern
+	for {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			name.WriteString(rest)
+			return name.String(), nil
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("index %s has an unclosed {", pattern)
+		}
+		name.WriteString(rest[:start])
+		name.WriteString(formatDate(rest[start+1:start+end], timestamp.UTC()))
+		rest = rest[start+end+1:]
+	}
+}
+
+// ValidateIndexName returns an error if an index name pattern can't be resolved
+func ValidateIndexName(pattern string) error {
+	_, err := IndexName(pattern, time.Unix(0, 0))
+	return err
+}
+
+// formatDate formats t using the date tokens in format. Other characters are kept as they are.
+func formatDate(format string, t time.Time) string {
+	formatted := &strings.Builder{}
+	for len(format) > 0 {
+		matched := false
+		for _, dt := range dateTokens {
+			if strings.HasPrefix(format, dt.token) {
+				formatted.WriteString(t.Format(dt.layout))
+				format = format[len(dt.token):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			formatted.WriteByte(format[0])
+			format = format[1:]
+		}
+	}
+	return formatted.String()
+}
@@ -0,0 +1,83 @@
+package es
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/Clever/kayvee-go.v6/logger"
+)
+
+func TestIndexName(t *testing.T) {
+	ts := time.Date(2024, 7, 16, 23, 30, 0, 0, time.FixedZone("PDT", -7*60*60))
+
+	tests := []struct {
+		pattern string
+		name    string
+	}{
+		{"events", "events"},
+		{"events-{yyyy.MM}", "events-2024.07"},
+		{"events-{yyyy.MM.dd}", "events-2024.07.17"},
+		{"events-{yy}-{MM}-{dd-HH}", "events-24-07-17-06"},
+	}
+	for _, test := range tests {
+		name, err := IndexName(test.pattern, ts)
+		assert.NoError(t, err, test.pattern)
+		assert.Equal(t, test.name, name, test.pattern)
+	}
+
+	_, err := IndexName("events-{yyyy.MM", ts)
+	assert.Error(t, err)
+	_, err = IndexName("events-{yyyy.MM}", time.Time{})
+	assert.Error(t, err)
+	name, err := IndexName("events", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "events", name)
+	assert.Error(t, ValidateIndexName("events-{yyyy"))
+}
+
+func TestWriteDocsTimeBasedIndices(t *testing.T) {
+	fake := newFakeES(t, nil)
+	db, err := NewDB(&DBConfig{URL: fake.URL}, []string{"events-{yyyy.MM}", "all-events"}, logger.New("test"))
+	require.NoError(t, err)
+
+	result, err := db.WriteDocs([]Doc{
+		{Op: OpTypeInsert, ID: "july", Item: map[string]interface{}{"a": "b"}, Timestamp: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{Op: OpTypeDelete, ID: "june", Timestamp: time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)},
+		{Op: OpTypeInsert, ID: "undated", Item: map[string]interface{}{"a": "b"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{2}, result.Failed())
+	assert.Equal(t, "invalid_index_name_exception", result.Docs[2].Indices[0].ErrorType)
+	assert.False(t, result.Docs[2].Indices[0].Retryable)
+
+	bulks := fake.Bulks()
+	require.Len(t, bulks, 1)
+	indices := []string{}
+	for _, action := range bulks[0] {
+		indices = append(indices, action.Index())
+	}
+	assert.Equal(t, []string{"events-2024.07", "all-events", "events-2024.06", "all-events", "all-events"}, indices)
+}
+
+func TestWriteDocsRoutedIndices(t *testing.T) {
+	fake := newFakeES(t, nil)
+	db, err := NewDB(&DBConfig{URL: fake.URL}, []string{"default"}, logger.New("test"))
+	require.NoError(t, err)
+
+	_, err = db.WriteDocs([]Doc{
+		{Op: OpTypeInsert, ID: "user", Item: map[string]interface{}{"a": "b"}, Indices: []string{"users", "entities"}},
+		{Op: OpTypeInsert, ID: "other", Item: map[string]interface{}{"a": "b"}},
+	})
+	require.NoError(t, err)
+
+	bulks := fake.Bulks()
+	require.Len(t, bulks, 1)
+	indices := []string{}
+	for _, action := range bulks[0] {
+		indices = append(indices, action.Index())
+	}
+	assert.Equal(t, []string{"users", "entities", "default"}, indices)
+}
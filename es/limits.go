@@ -0,0 +1,274 @@
+package es
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Policies for documents that exceed their limits
+const (
+	// LimitPolicyReject fails the document, so that it is sent to the dead letter sink
+	LimitPolicyReject = "reject"
+	// LimitPolicyTruncate truncates long strings
+	LimitPolicyTruncate = "truncate"
+	// LimitPolicyDrop removes the objects and lists nested too deep, then the largest top-level
+	// attributes until the document fits
+	LimitPolicyDrop = "drop"
+	// LimitPolicyStub indexes only the top-level attributes that aren't objects or lists
+	LimitPolicyStub = "stub"
+)
+
+// LimitsConfig limits the size of each document. Documents that are changed to fit are marked
+// with "_truncated": true. Documents that still don't fit are rejected.
+type LimitsConfig struct {
+	// MaxBytes of a document's JSON. Unlimited if zero.
+	MaxBytes int
+	// MaxFields is the most fields a document may have, counting the fields of nested objects. Unlimited if zero.
+	MaxFields int
+	// MaxDepth is the deepest objects and lists may be nested. Unlimited if zero.
+	MaxDepth int
+	// MaxStringBytes is the length long strings are truncated to, with the truncate policy
+	MaxStringBytes int
+	// Policy is reject, truncate, drop or stub. Defaults to reject.
+	Policy string
+}
+
+// limitError is the error type of documents that exceed their limits
+const limitError = "document_limit_exceeded_exception"
+
+// enabled returns true if any limit is set
+func (c LimitsConfig) enabled() bool {
+	return c.MaxBytes > 0 || c.MaxFields > 0 || c.MaxDepth > 0
+}
+
+// apply returns the item changed to fit the limits according to the policy, or an error if it doesn't fit
+func (c LimitsConfig) apply(item map[string]interface{}) (map[string]interface{}, error) {
+	exceeded, err := c.exceeded(item)
+	if err != nil || exceeded == "" {
+		return item, err
+	}
+
+	switch c.Policy {
+	case LimitPolicyTruncate:
+		item = truncateStrings(item, c.MaxStringBytes).(map[string]interface{})
+	case LimitPolicyDrop:
+		// the item is changed in place, and may be shared with the caller
+		if item, err = c.drop(copyValue(item).(map[string]interface{})); err != nil {
+			return nil, err
+		}
+	case LimitPolicyStub:
+		stub := map[string]interface{}{}
+		for k, v := range item {
+			switch v.(type) {
+			case map[string]interface{}, []interface{}:
+			default:
+				stub[k] = v
+			}
+		}
+		item = stub
+	default:
+		return nil, fmt.Errorf("document exceeds %s", exceeded)
+	}
+
+	item["_truncated"] = true
+	if exceeded, err = c.exceeded(item); err != nil {
+		return nil, err
+	} else if exceeded != "" {
+		return nil, fmt.Errorf("document exceeds %s after applying the %s policy", exceeded, c.Policy)
+	}
+	return item, nil
+}
+
+// exceeded returns the first limit the item exceeds, or "" if it fits
+func (c LimitsConfig) exceeded(item map[string]interface{}) (string, error) {
+	if c.MaxDepth > 0 && depth(item) > c.MaxDepth {
+		return fmt.Sprintf("the maximum depth of %d", c.MaxDepth), nil
+	}
+	if c.MaxFields > 0 && countFields(item) > c.MaxFields {
+		return fmt.Sprintf("the maximum of %d fields", c.MaxFields), nil
+	}
+	if c.MaxBytes > 0 {
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return "", err
+		}
+		if len(encoded) > c.MaxBytes {
+			return fmt.Sprintf("the maximum of %d bytes", c.MaxBytes), nil
+		}
+	}
+	return "", nil
+}
+
+// drop removes the objects and lists nested deeper than MaxDepth, then the largest top-level attributes
+// until the item fits.
+// The paths of the removed attributes are listed in "_dropped".
+func (c LimitsConfig) drop(item map[string]interface{}) (map[string]interface{}, error) {
+	dropped := []string{}
+	if c.MaxDepth > 0 {
+		dropped = dropDeep(item, "", 1, c.MaxDepth)
+	}
+	for {
+		// the markers count towards the limits too
+		sort.Strings(dropped)
+		item["_truncated"], item["_dropped"] = true, dropped
+		exceeded, err := c.exceeded(item)
+		if err != nil || exceeded == "" {
+			return item, err
+		}
+		delete(item, "_truncated")
+		delete(item, "_dropped")
+		name, err := removeLargest(item, c.MaxFields > 0 && countFields(item) > c.MaxFields)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			return item, nil
+		}
+		dropped = append(dropped, name)
+	}
+}
+
+// depth returns how deeply objects and lists are nested in value, counting value itself
+func depth(value interface{}) int {
+	deepest := 0
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, nested := range v {
+			if d := depth(nested); d > deepest {
+				deepest = d
+			}
+		}
+	case []interface{}:
+		for _, nested := range v {
+			if d := depth(nested); d > deepest {
+				deepest = d
+			}
+		}
+	default:
+		return 0
+	}
+	return deepest + 1
+}
+
+// countFields returns the number of object fields in value, including those of nested objects
+func countFields(value interface{}) int {
+	count := 0
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, nested := range v {
+			count += 1 + countFields(nested)
+		}
+	case []interface{}:
+		for _, nested := range v {
+			count += countFields(nested)
+		}
+	}
+	return count
+}
+
+// truncateStrings returns value with every string longer than max bytes truncated
+func truncateStrings(value interface{}, max int) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		truncated := map[string]interface{}{}
+		for k, nested := range v {
+			truncated[k] = truncateStrings(nested, max)
+		}
+		return truncated
+	case []interface{}:
+		truncated := make([]interface{}, len(v))
+		for i, nested := range v {
+			truncated[i] = truncateStrings(nested, max)
+		}
+		return truncated
+	case []string:
+		// string sets
+		truncated := make([]string, len(v))
+		for i, nested := range v {
+			truncated[i] = truncateStrings(nested, max).(string)
+		}
+		return truncated
+	case string:
+		if max <= 0 || len(v) <= max {
+			return v
+		}
+		// don't split a multi-byte character
+		return strings.ToValidUTF8(v[:max], "")
+	default:
+		return v
+	}
+}
+
+// copyValue returns a copy of value, copying nested objects and lists
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := map[string]interface{}{}
+		for k, nested := range v {
+			copied[k] = copyValue(nested)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, nested := range v {
+			copied[i] = copyValue(nested)
+		}
+		return copied
+	case []string:
+		return append([]string{}, v...)
+	default:
+		return v
+	}
+}
+
+// dropDeep removes the objects and lists nested deeper than max from m at the given depth,
+// returning their paths. Lists are dropped whole.
+func dropDeep(m map[string]interface{}, prefix string, d, max int) []string {
+	dropped := []string{}
+	for k, v := range m {
+		path := joinPath(prefix, k)
+		if depth(v) == 0 {
+			continue
+		}
+		if d+depth(v) > max {
+			if nested, ok := v.(map[string]interface{}); ok && d < max {
+				dropped = append(dropped, dropDeep(nested, path, d+1, max)...)
+				continue
+			}
+			delete(m, k)
+			dropped = append(dropped, path)
+		}
+	}
+	return dropped
+}
+
+// removeLargest removes the top-level attribute with the largest JSON encoding, or the most fields
+// if byFields is set, returning its name, or "" if m is empty
+func removeLargest(m map[string]interface{}, byFields bool) (string, error) {
+	largest, largestSize := "", -1
+	for k, v := range m {
+		size := countFields(v)
+		if !byFields {
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return "", err
+			}
+			size = len(encoded)
+		}
+		if size > largestSize || (size == largestSize && k < largest) {
+			largest, largestSize = k, size
+		}
+	}
+	delete(m, largest)
+	return largest, nil
+}
+
+// joinPath appends an attribute name to a dotted path
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,110 @@
+package es
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testItem() map[string]interface{} {
+	return map[string]interface{}{
+		"id":   "1",
+		"long": strings.Repeat("é", 50),
+		"nested": map[string]interface{}{
+			"a": "b",
+			"deep": map[string]interface{}{
+				"list": []interface{}{"x", "y"},
+			},
+		},
+		"list": []interface{}{map[string]interface{}{"c": "d"}},
+	}
+}
+
+func TestLimitsMeasures(t *testing.T) {
+	assert.Equal(t, 4, depth(testItem()))
+	// id, long, nested, nested.a, nested.deep, nested.deep.list, list, list.c
+	assert.Equal(t, 8, countFields(testItem()))
+}
+
+func TestLimitsApply(t *testing.T) {
+	item := testItem()
+	limited, err := LimitsConfig{MaxBytes: 1000, MaxFields: 8, MaxDepth: 4}.apply(item)
+	require.NoError(t, err)
+	assert.Equal(t, testItem(), limited)
+
+	_, err = LimitsConfig{MaxFields: 7, Policy: LimitPolicyReject}.apply(item)
+	assert.EqualError(t, err, "document exceeds the maximum of 7 fields")
+
+	limited, err = LimitsConfig{MaxBytes: 150, MaxStringBytes: 11, Policy: LimitPolicyTruncate}.apply(item)
+	require.NoError(t, err)
+	// truncated to whole characters
+	assert.Equal(t, strings.Repeat("é", 5), limited["long"])
+	assert.Equal(t, true, limited["_truncated"])
+	assert.Equal(t, testItem(), item, "the item is not changed")
+
+	// string sets are converted to []string
+	set := map[string]interface{}{"ss": []string{strings.Repeat("x", 200), "y"}}
+	limited, err = LimitsConfig{MaxBytes: 100, MaxStringBytes: 20, Policy: LimitPolicyTruncate}.apply(set)
+	require.NoError(t, err)
+	assert.Equal(t, []string{strings.Repeat("x", 20), "y"}, limited["ss"])
+	assert.Len(t, set["ss"].([]string)[0], 200, "the item is not changed")
+
+	_, err = LimitsConfig{MaxDepth: 3, MaxStringBytes: 11, Policy: LimitPolicyTruncate}.apply(item)
+	assert.EqualError(t, err, "document exceeds the maximum depth of 3 after applying the truncate policy")
+
+	limited, err = LimitsConfig{MaxDepth: 2, Policy: LimitPolicyDrop}.apply(item)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"id":         "1",
+		"long":       strings.Repeat("é", 50),
+		"nested":     map[string]interface{}{"a": "b"},
+		"_truncated": true,
+		"_dropped":   []string{"list", "nested.deep"},
+	}, limited)
+	assert.Equal(t, testItem(), item, "the item is not changed")
+
+	limited, err = LimitsConfig{MaxBytes: 120, Policy: LimitPolicyDrop}.apply(item)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"long"}, limited["_dropped"])
+	limited, err = LimitsConfig{MaxFields: 6, Policy: LimitPolicyDrop}.apply(item)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nested"}, limited["_dropped"])
+
+	limited, err = LimitsConfig{MaxDepth: 1, Policy: LimitPolicyStub}.apply(item)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": "1", "long": strings.Repeat("é", 50), "_truncated": true}, limited)
+
+	_, err = LimitsConfig{MaxBytes: 10, Policy: LimitPolicyStub}.apply(item)
+	assert.Error(t, err)
+}
+
+func TestWriteDocsLimits(t *testing.T) {
+	for _, impl := range []string{APIv6, APITypeless} {
+		t.Run(impl, func(t *testing.T) {
+			fake := implementations[impl].newFake(t, nil)
+			db := mustOpen(t, implementations[impl], &DBConfig{
+				URL:    fake.URL,
+				Limits: LimitsConfig{MaxFields: 7},
+			}, []string{"index-1", "index-2"})
+
+			result, err := db.WriteDocs([]Doc{
+				{Op: OpTypeInsert, ID: "small", Item: map[string]interface{}{"a": "b"}},
+				{Op: OpTypeInsert, ID: "large", Item: testItem()},
+				{Op: OpTypeDelete, ID: "deleted", Item: testItem()},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []int{1}, result.Failed())
+			require.Len(t, result.Docs[1].Indices, 2)
+			for _, index := range result.Docs[1].Indices {
+				assert.Equal(t, limitError, index.ErrorType)
+				assert.False(t, index.Retryable)
+			}
+
+			bulks := fake.Bulks()
+			require.Len(t, bulks, 1)
+			assert.Len(t, bulks[0], 4)
+		})
+	}
+}
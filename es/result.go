@@ -0,0 +1,78 @@
+package es
+
+import "net/http"
+
+// IndexResult is the outcome of writing a Doc to a single index
+type IndexResult struct {
+	Index  string
+	Status int
+	// ErrorType and Reason are the error reported by Elasticsearch, if any
+	ErrorType string
+	Reason    string
+	// Retryable is true if the write may succeed if it is tried again
+	Retryable bool
+	// Stale is true if the write was rejected because the index holds a newer version of the Doc.
+	// Stale writes are not failures.
+	Stale bool
+}
+
+// Failed returns true if the Doc was not written to the index
+func (r IndexResult) Failed() bool {
+	return !r.Stale && (r.Status < 200 || r.Status > 299)
+}
+
+// DocResult is the outcome of writing a Doc to each of its indices
+type DocResult struct {
+	ID      string
+	Indices []IndexResult
+}
+
+// Failed returns true if the Doc was not written to at least one of its indices
+func (r DocResult) Failed() bool {
+	for _, index := range r.Indices {
+		if index.Failed() {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteResult reports the outcome of writing a batch of Doc's
+type WriteResult struct {
+	// Docs holds one DocResult per Doc, in the order they were written
+	Docs []DocResult
+}
+
+// Failed returns the position in the batch of every Doc that failed to be
+// written to at least one index, in ascending order
+func (r WriteResult) Failed() []int {
+	failed := []int{}
+	for i, doc := range r.Docs {
+		if doc.Failed() {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
+
+// Stale returns the number of writes rejected because the index held a newer version of the Doc
+func (r WriteResult) Stale() int {
+	stale := 0
+	for _, doc := range r.Docs {
+		for _, index := range doc.Indices {
+			if index.Stale {
+				stale++
+			}
+		}
+	}
+	return stale
+}
+
+// isRetryable reports if a bulk item that failed with the given status and
+// error type may succeed if it is tried again
+func isRetryable(status int, errorType string) bool {
+	if errorType == "es_rejected_execution_exception" {
+		return true
+	}
+	return status == http.StatusTooManyRequests || status >= 500
+}
@@ -0,0 +1,52 @@
+package es
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/Clever/kayvee-go.v6/logger"
+)
+
+func TestWriteDocsResult(t *testing.T) {
+	fake := newFakeES(t, func(action fakeBulkAction) (int, string) {
+		switch {
+		case action.ID() == "bad" && action.Index() == "index-2":
+			return 400, "mapper_parsing_exception"
+		case action.ID() == "busy":
+			return 429, "es_rejected_execution_exception"
+		case action.ID() == "missing":
+			return 404, ""
+		}
+		return 201, ""
+	})
+
+	db, err := NewDB(&DBConfig{URL: fake.URL}, []string{"index-1", "index-2"}, logger.New("test"))
+	require.NoError(t, err)
+
+	result, err := db.WriteDocs([]Doc{
+		{Op: OpTypeInsert, ID: "good", Item: map[string]interface{}{"a": "b"}},
+		{Op: OpTypeUpdate, ID: "bad", Item: map[string]interface{}{"a": "b"}},
+		{Op: OpTypeDelete, ID: "missing"},
+		{Op: OpTypeInsert, ID: "busy", Item: map[string]interface{}{"a": "b"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{1, 3}, result.Failed())
+	require.Len(t, result.Docs, 4)
+	assert.Equal(t, DocResult{
+		ID: "bad",
+		Indices: []IndexResult{
+			{Index: "index-1", Status: 201},
+			{
+				Index:     "index-2",
+				Status:    400,
+				ErrorType: "mapper_parsing_exception",
+				Reason:    "mapper_parsing_exception reason",
+				Retryable: false,
+			},
+		},
+	}, result.Docs[1])
+	assert.False(t, result.Docs[2].Failed())
+	assert.True(t, result.Docs[3].Indices[0].Retryable)
+}
@@ -0,0 +1,33 @@
+package es
+
+import (
+	"math/rand"
+	"time"
+)
+
+// RetryConfig specifies how bulk items that fail with a retryable error are re-submitted
+type RetryConfig struct {
+	// MaxRetries is the number of times a failed item is re-submitted. Zero disables retries.
+	MaxRetries int
+	// InitialBackoff is the wait before the first retry. It doubles on every retry after that.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries
+	MaxBackoff time.Duration
+	// Jitter is the fraction, between 0 and 1, of each wait that is randomized
+	Jitter float64
+}
+
+// backoff returns how long to wait before the given retry, starting at 1
+func (c RetryConfig) backoff(retry int) time.Duration {
+	wait := c.InitialBackoff
+	for i := 1; i < retry && (c.MaxBackoff <= 0 || wait < c.MaxBackoff); i++ {
+		wait *= 2
+	}
+	if c.MaxBackoff > 0 && wait > c.MaxBackoff {
+		wait = c.MaxBackoff
+	}
+	if c.Jitter > 0 && wait > 0 {
+		wait -= time.Duration(rand.Int63n(int64(float64(wait)*c.Jitter) + 1))
+	}
+	return wait
+}
@@ -0,0 +1,102 @@
+package es
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/Clever/kayvee-go.v6/logger"
+)
+
+func TestBackoff(t *testing.T) {
+	config := RetryConfig{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	assert.Equal(t, 100*time.Millisecond, config.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, config.backoff(2))
+	assert.Equal(t, 800*time.Millisecond, config.backoff(4))
+	assert.Equal(t, time.Second, config.backoff(5))
+	assert.Equal(t, time.Second, config.backoff(100))
+
+	config.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		wait := config.backoff(2)
+		assert.True(t, wait >= 100*time.Millisecond && wait <= 200*time.Millisecond, wait)
+	}
+}
+
+func TestWriteDocsRetriesRetryableFailures(t *testing.T) {
+	attempts := map[string]int{}
+	fake := newFakeES(t, func(action fakeBulkAction) (int, string) {
+		attempts[action.ID()]++
+		switch action.ID() {
+		case "rejected":
+			if attempts["rejected"] < 3 {
+				return 429, "es_rejected_execution_exception"
+			}
+		case "unavailable":
+			return 503, "unavailable_shards_exception"
+		case "bad":
+			return 400, "mapper_parsing_exception"
+		}
+		return 201, ""
+	})
+
+	db, err := NewDB(&DBConfig{
+		URL:   fake.URL,
+		Retry: RetryConfig{MaxRetries: 3, InitialBackoff: time.Second},
+	}, []string{"index"}, logger.New("test"))
+	require.NoError(t, err)
+	waits := []time.Duration{}
+	db.sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	item := map[string]interface{}{"a": "b"}
+	result, err := db.WriteDocs([]Doc{
+		{Op: OpTypeInsert, ID: "good", Item: item},
+		{Op: OpTypeInsert, ID: "rejected", Item: item},
+		{Op: OpTypeInsert, ID: "unavailable", Item: item},
+		{Op: OpTypeInsert, ID: "bad", Item: item},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{2, 3}, result.Failed())
+	assert.Equal(t, map[string]int{"good": 1, "rejected": 3, "unavailable": 4, "bad": 1}, attempts)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}, waits)
+	assert.True(t, result.Docs[2].Indices[0].Retryable)
+	assert.False(t, result.Docs[3].Indices[0].Retryable)
+
+	bulks := fake.Bulks()
+	require.Len(t, bulks, 4)
+	assert.Len(t, bulks[0], 4)
+	assert.Len(t, bulks[1], 2)
+	assert.Len(t, bulks[3], 1)
+}
+
+func TestWriteDocsRetriesLaterWritesOfTheSameDocument(t *testing.T) {
+	rejected := false
+	fake := newFakeES(t, func(action fakeBulkAction) (int, string) {
+		if action.ID() == "doc" && string(action.Source) == `{"v":1}` && !rejected {
+			rejected = true
+			return 429, "es_rejected_execution_exception"
+		}
+		return 201, ""
+	})
+
+	db, err := NewDB(&DBConfig{URL: fake.URL, Retry: RetryConfig{MaxRetries: 1}}, []string{"index"}, logger.New("test"))
+	require.NoError(t, err)
+	db.sleep = func(time.Duration) {}
+
+	result, err := db.WriteDocs([]Doc{
+		{Op: OpTypeInsert, ID: "doc", Item: map[string]interface{}{"v": 1}},
+		{Op: OpTypeInsert, ID: "other", Item: map[string]interface{}{"v": 1}},
+		{Op: OpTypeUpdate, ID: "doc", Item: map[string]interface{}{"v": 2}},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, result.Failed())
+
+	// the newer write is retried after the older one, so it's the one kept
+	bulks := fake.Bulks()
+	require.Len(t, bulks, 2)
+	require.Len(t, bulks[1], 2)
+	assert.JSONEq(t, `{"v":1}`, string(bulks[1][0].Source))
+	assert.JSONEq(t, `{"v":2}`, string(bulks[1][1].Source))
+}
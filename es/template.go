@@ -0,0 +1,302 @@
+package es
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"gopkg.in/Clever/kayvee-go.v6/logger"
+)
+
+// Template declares the settings and mappings of the indices written to, so that they don't rely on
+// dynamic mapping. Mappings are typeless; the v6 API nests them under the mapping type.
+type Template struct {
+	Settings map[string]interface{} `json:"settings,omitempty"`
+	Mappings map[string]interface{} `json:"mappings,omitempty"`
+}
+
+// BootstrapConfig specifies the indices created from a Template
+type BootstrapConfig struct {
+	Template Template
+	// Indices bootstrapped, as configured. Names with date patterns, such as "events-{yyyy.MM}", get an
+	// index template matching every index they resolve to. Defaults to the indices the DB writes to.
+	Indices []string
+}
+
+// Drift is a mapping parameter of an index or index template that differs from the Template
+type Drift struct {
+	// Index or index template name
+	Index string
+	// Path of the parameter in the mappings, such as "properties.count.type"
+	Path     string
+	Declared interface{}
+	// Live is nil if the parameter is missing
+	Live interface{}
+}
+
+func (d Drift) String() string {
+	return fmt.Sprintf("%s: %s is %v, declared %v", d.Index, d.Path, d.Live, d.Declared)
+}
+
+// Bootstrapper is implemented by DBs that can create the indices they write to
+type Bootstrapper interface {
+	// Bootstrap creates the indices and index templates missing from the cluster, and returns where the
+	// mappings of those that exist drift from the template. Only declared parameters are compared,
+	// so fields added by dynamic mapping aren't drift.
+	Bootstrap(config BootstrapConfig) ([]Drift, error)
+}
+
+// Bootstrap implements Bootstrapper
+func (db *writer) Bootstrap(config BootstrapConfig) ([]Drift, error) {
+	indices := config.Indices
+	if len(indices) == 0 {
+		indices = db.indices
+	}
+	drift := []Drift{}
+	seen := map[string]bool{}
+	for _, pattern := range indices {
+		// index names are lower-cased when written to
+		pattern = strings.ToLower(pattern)
+		if seen[pattern] {
+			continue
+		}
+		seen[pattern] = true
+
+		var live map[string]interface{}
+		var err error
+		if strings.Contains(pattern, "{") {
+			live, err = db.bootstrapTemplate(pattern, config.Template)
+		} else {
+			live, err = db.bootstrapIndex(pattern, config.Template)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", pattern, err)
+		}
+		if live != nil {
+			drift = append(drift, diffMappings(templateName(pattern), "", config.Template.Mappings, live)...)
+		}
+	}
+	return drift, nil
+}
+
+// bootstrap bootstraps the indices when the DB is created, logging drift
+func (db *writer) bootstrap() error {
+	if db.config.Bootstrap == nil {
+		return nil
+	}
+	drift, err := db.Bootstrap(*db.config.Bootstrap)
+	if err != nil {
+		return fmt.Errorf("Could not bootstrap indices: %s", err)
+	}
+	for _, d := range drift {
+		db.lg.WarnD("mapping-drift", logger.M{
+			"index":    d.Index,
+			"path":     d.Path,
+			"declared": fmt.Sprint(d.Declared),
+			"live":     fmt.Sprint(d.Live),
+		})
+	}
+	return nil
+}
+
+// bootstrapIndex creates the index if it's missing, or the index behind it if it's a write alias,
+// otherwise returning its live mappings
+func (db *writer) bootstrapIndex(index string, template Template) (map[string]interface{}, error) {
+	status, body, err := db.perform(http.MethodGet, "/"+url.PathEscape(index)+"/_mapping", nil)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		create := map[string]interface{}{"mappings": db.typedMappings(template.Mappings)}
+		if len(template.Settings) > 0 {
+			create["settings"] = template.Settings
+		}
+		name := index
+		if db.config.Aliases != nil {
+			// the first version of the index behind the write alias
+			name = index + "-v1"
+			create["aliases"] = map[string]interface{}{index: map[string]interface{}{}}
+		}
+		if _, _, err := db.perform(http.MethodPut, "/"+url.PathEscape(name), create); err != nil {
+			return nil, err
+		}
+		db.lg.InfoD("index-created", logger.M{"index": name})
+		return nil, nil
+	}
+
+	// keyed by the concrete index, which differs from the name written to if it's an alias
+	resp := map[string]struct {
+		Mappings map[string]interface{} `json:"mappings"`
+	}{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid mapping response: %s", err)
+	}
+	for _, m := range resp {
+		return db.untypedMappings(m.Mappings), nil
+	}
+	return map[string]interface{}{}, nil
+}
+
+// bootstrapTemplate creates the index template for an index name with date patterns if it's missing,
+// otherwise returning its live mappings
+func (db *writer) bootstrapTemplate(pattern string, template Template) (map[string]interface{}, error) {
+	name := templateName(pattern)
+	path := "/_index_template/" + url.PathEscape(name)
+	if db.mappingType != "" {
+		path = "/_template/" + url.PathEscape(name)
+	}
+	status, body, err := db.perform(http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		body := map[string]interface{}{"mappings": db.typedMappings(template.Mappings)}
+		if len(template.Settings) > 0 {
+			body["settings"] = template.Settings
+		}
+		if db.mappingType == "" {
+			// composable index templates nest the index's settings and mappings
+			body = map[string]interface{}{"template": body}
+		}
+		body["index_patterns"] = []string{indexPattern(pattern)}
+		if _, _, err := db.perform(http.MethodPut, path, body); err != nil {
+			return nil, err
+		}
+		db.lg.InfoD("index-template-created", logger.M{"template": name, "index": pattern})
+		return nil, nil
+	}
+
+	if db.mappingType != "" {
+		resp := map[string]struct {
+			Mappings map[string]interface{} `json:"mappings"`
+		}{}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("invalid index template response: %s", err)
+		}
+		return db.untypedMappings(resp[name].Mappings), nil
+	}
+	resp := struct {
+		IndexTemplates []struct {
+			IndexTemplate struct {
+				Template struct {
+					Mappings map[string]interface{} `json:"mappings"`
+				} `json:"template"`
+			} `json:"index_template"`
+		} `json:"index_templates"`
+	}{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid index template response: %s", err)
+	}
+	if len(resp.IndexTemplates) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	return resp.IndexTemplates[0].IndexTemplate.Template.Mappings, nil
+}
+
+// typedMappings nests mappings under the mapping type, if the cluster has them
+func (db *writer) typedMappings(mappings map[string]interface{}) map[string]interface{} {
+	if mappings == nil {
+		mappings = map[string]interface{}{}
+	}
+	if db.mappingType == "" {
+		return mappings
+	}
+	return map[string]interface{}{db.mappingType: mappings}
+}
+
+// untypedMappings returns the mappings of the mapping type, if the cluster has them
+func (db *writer) untypedMappings(mappings map[string]interface{}) map[string]interface{} {
+	if db.mappingType == "" {
+		return mappings
+	}
+	typed, _ := mappings[db.mappingType].(map[string]interface{})
+	return typed
+}
+
+// templateName returns the name of the index template for an index name with date patterns,
+// such as "events-yyyy.mm" for "events-{yyyy.MM}". Other names are returned unchanged.
+func templateName(pattern string) string {
+	return strings.NewReplacer("{", "", "}", "").Replace(pattern)
+}
+
+// indexPattern returns the wildcard pattern matching every index an index name with date
+// patterns resolves to, such as "events-*" for "events-{yyyy.MM}"
+func indexPattern(pattern string) string {
+	matched := &strings.Builder{}
+	for {
+		start := strings.Index(pattern, "{")
+		end := strings.Index(pattern, "}")
+		if start < 0 || end < start {
+			matched.WriteString(pattern)
+			return matched.String()
+		}
+		matched.WriteString(pattern[:start])
+		matched.WriteString("*")
+		pattern = pattern[end+1:]
+	}
+}
+
+// diffMappings returns the drift of every parameter declared that differs in live. Objects are
+// compared parameter by parameter, so parameters only set in live aren't drift, while anything else
+// is compared whole.
+func diffMappings(index, prefix string, declared, live map[string]interface{}) []Drift {
+	keys := []string{}
+	for k := range declared {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	drift := []Drift{}
+	for _, k := range keys {
+		path := joinPath(prefix, k)
+		d := declared[k]
+		l, ok := live[k]
+		dMap, dIsMap := d.(map[string]interface{})
+		lMap, lIsMap := l.(map[string]interface{})
+		if dIsMap && lIsMap {
+			drift = append(drift, diffMappings(index, path, dMap, lMap)...)
+			continue
+		}
+		if !ok || !sameValue(d, l) {
+			drift = append(drift, Drift{Index: index, Path: path, Declared: d, Live: l})
+		}
+	}
+	return drift
+}
+
+// sameValue returns true if a and b are equal, ignoring whether scalars are strings, as
+// Elasticsearch returns some parameters, such as "dynamic": "false", as strings
+func sameValue(a, b interface{}) bool {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			if nested, ok := b[k]; !ok || !sameValue(v, nested) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !sameValue(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	switch b.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
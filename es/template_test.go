@@ -0,0 +1,46 @@
+package es
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateNames(t *testing.T) {
+	assert.Equal(t, "events-yyyy.mm", templateName("events-{yyyy.mm}"))
+	assert.Equal(t, "events-*", indexPattern("events-{yyyy.mm}"))
+	assert.Equal(t, "events-*-*", indexPattern("events-{yyyy}-{mm}"))
+	assert.Equal(t, "users", indexPattern("users"))
+}
+
+func TestDiffMappings(t *testing.T) {
+	declared := map[string]interface{}{
+		"dynamic": false,
+		"dynamic_templates": []interface{}{
+			map[string]interface{}{"strings": map[string]interface{}{"match_mapping_type": "string"}},
+		},
+		"properties": map[string]interface{}{
+			"count": map[string]interface{}{"type": "long"},
+			"tags":  map[string]interface{}{"type": "keyword"},
+		},
+	}
+	live := map[string]interface{}{
+		// Elasticsearch returns some parameters as strings
+		"dynamic": "false",
+		"dynamic_templates": []interface{}{
+			map[string]interface{}{"strings": map[string]interface{}{"match_mapping_type": "string"}},
+		},
+		"properties": map[string]interface{}{
+			"count": map[string]interface{}{"type": "long"},
+			"tags":  "keyword",
+			// fields added by dynamic mapping aren't drift
+			"added": map[string]interface{}{"type": "text"},
+		},
+	}
+	assert.Equal(t, []Drift{
+		{Index: "i", Path: "properties.tags", Declared: map[string]interface{}{"type": "keyword"}, Live: "keyword"},
+	}, diffMappings("i", "", declared, live))
+
+	live["dynamic_templates"] = []interface{}{}
+	assert.Len(t, diffMappings("i", "", declared, live), 2)
+}
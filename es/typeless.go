@@ -0,0 +1,148 @@
+package es
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"gopkg.in/Clever/kayvee-go.v6/logger"
+	elastic "gopkg.in/olivere/elastic.v6"
+)
+
+// transportBackoff is how long requests that could not be sent, or were rejected as a whole with
+// a retryable status, are retried after. It matches the v6 client's retrier.
+var transportBackoff = []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+
+// Typeless writes to clusters without mapping types: Elasticsearch 7 and 8, and OpenSearch.
+// It speaks the bulk API over HTTP, as the v6 client sends mapping types.
+type Typeless struct {
+	client *http.Client
+	url    string
+	writer
+}
+
+// NewTypelessDB creates a DB for clusters without mapping types
+func NewTypelessDB(config *DBConfig, indices []string, lg logger.KayveeLogger) (*Typeless, error) {
+	client, err := httpClient(config)
+	if err != nil {
+		return nil, err
+	}
+	db := &Typeless{
+		client: client,
+		url:    strings.TrimSuffix(config.URL, "/"),
+		writer: writer{
+			config:  config,
+			indices: indices,
+			lg:      lg,
+			sleep:   time.Sleep,
+		},
+	}
+	db.send = db.sendBulk
+	db.perform = db.performJSON
+
+	// fail fast on an unreachable cluster, as the v6 client does
+	resp, err := db.do(http.MethodGet, "/", "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("Could not connect to cluster: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Could not connect to cluster: status %d", resp.StatusCode)
+	}
+	if err := db.bootstrap(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// sendBulk sends the requests to the bulk API, retrying requests that could not be sent
+func (db *Typeless) sendBulk(reqs []elastic.BulkableRequest) (*elastic.BulkResponse, error) {
+	body := bytes.Buffer{}
+	for _, req := range reqs {
+		lines, err := req.Source()
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			body.WriteString(line)
+			body.WriteByte('\n')
+		}
+	}
+
+	for retry := 0; ; retry++ {
+		bulkResponse, retryable, err := db.postBulk(body.Bytes())
+		if err == nil || !retryable || retry >= len(transportBackoff) {
+			return bulkResponse, err
+		}
+		db.sleep(transportBackoff[retry])
+	}
+}
+
+// postBulk posts a bulk request body. retryable is true if it could not be sent, or was rejected
+// with a retryable status.
+func (db *Typeless) postBulk(body []byte) (resp *elastic.BulkResponse, retryable bool, err error) {
+	httpResp, err := db.do(http.MethodPost, "/_bulk", "application/x-ndjson", body)
+	if err != nil {
+		return nil, true, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		reason, _ := ioutil.ReadAll(io.LimitReader(httpResp.Body, 1024))
+		return nil, isRetryable(httpResp.StatusCode, ""),
+			fmt.Errorf("bulk request failed with status %d: %s", httpResp.StatusCode, reason)
+	}
+	resp = &elastic.BulkResponse{}
+	if err := json.NewDecoder(httpResp.Body).Decode(resp); err != nil {
+		return nil, false, fmt.Errorf("invalid bulk response: %s", err)
+	}
+	return resp, false, nil
+}
+
+// performJSON sends a request with a JSON body, returning an error unless it succeeds or is not found
+func (db *Typeless) performJSON(method, path string, body interface{}) (int, []byte, error) {
+	var encoded []byte
+	contentType := ""
+	if body != nil {
+		var err error
+		if encoded, err = json.Marshal(body); err != nil {
+			return 0, nil, err
+		}
+		contentType = "application/json"
+	}
+	resp, err := db.do(method, path, contentType, encoded)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		if len(respBody) > 1024 {
+			respBody = respBody[:1024]
+		}
+		return 0, nil, fmt.Errorf("%s %s failed with status %d: %s", method, path, resp.StatusCode, respBody)
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// do sends a request to the cluster
+func (db *Typeless) do(method, path, contentType string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, db.url+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if auth := db.config.Auth; auth.Username != "" {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+	return db.client.Do(req)
+}
@@ -0,0 +1,60 @@
+package es
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/Clever/kayvee-go.v6/logger"
+)
+
+func TestTypelessRetriesRejectedBulkRequests(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/_bulk") {
+			w.Write([]byte(`{"version":{"number":"8.11.0"}}`))
+			return
+		}
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		attempts++
+		if attempts < 3 {
+			http.Error(w, `{"error":"too many requests"}`, http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"errors":false,"items":[{"index":{"_index":"index","_id":"1","status":201}}]}`))
+	}))
+	defer server.Close()
+
+	db, err := NewTypelessDB(&DBConfig{URL: server.URL + "/"}, []string{"index"}, logger.New("test"))
+	require.NoError(t, err)
+	waits := []time.Duration{}
+	db.sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	result, err := db.WriteDocs([]Doc{{Op: OpTypeInsert, ID: "1", Item: map[string]interface{}{"a": "b"}}})
+	require.NoError(t, err)
+	assert.Empty(t, result.Failed())
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, waits)
+}
+
+func TestTypelessReportsBadRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/_bulk") {
+			w.Write([]byte(`{"version":{"number":"8.11.0"}}`))
+			return
+		}
+		http.Error(w, `{"error":"bad request"}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	db, err := NewTypelessDB(&DBConfig{URL: server.URL}, []string{"index"}, logger.New("test"))
+	require.NoError(t, err)
+	db.sleep = func(time.Duration) { t.Error("bad requests should not be retried") }
+
+	_, err = db.WriteDocs([]Doc{{Op: OpTypeInsert, ID: "1", Item: map[string]interface{}{"a": "b"}}})
+	assert.Error(t, err)
+}
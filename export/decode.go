@@ -0,0 +1,62 @@
+package export
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Decoder reads the items of a data file in turn
+type Decoder interface {
+	// Decode returns the next item, or io.EOF after the last
+	Decode() (map[string]events.DynamoDBAttributeValue, error)
+}
+
+// NewDecoder creates a Decoder of the items of a data file in the export format.
+// Data files are gzip'd, but are also read if they have been decompressed.
+func NewDecoder(r io.Reader, format string) (Decoder, error) {
+	buffered := bufio.NewReader(r)
+	if magic, _ := buffered.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		buffered = bufio.NewReader(gz)
+	}
+
+	switch format {
+	case FormatDynamoDBJSON:
+		return &jsonDecoder{decoder: json.NewDecoder(buffered)}, nil
+	case FormatION:
+		return &ionDecoder{r: buffered}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// jsonDecoder reads DynamoDB JSON, a line per item such as {"Item":{"id":{"S":"1"}}}
+type jsonDecoder struct {
+	decoder *json.Decoder
+	item    int
+}
+
+func (d *jsonDecoder) Decode() (map[string]events.DynamoDBAttributeValue, error) {
+	line := struct {
+		Item map[string]events.DynamoDBAttributeValue
+	}{}
+	if err := d.decoder.Decode(&line); err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, fmt.Errorf("item %d: %s", d.item+1, err)
+	}
+	d.item++
+	if line.Item == nil {
+		return nil, fmt.Errorf("item %d: missing Item", d.item)
+	}
+	return line.Item, nil
+}
@@ -0,0 +1,155 @@
+// Package export reads the items of a DynamoDB table export to S3
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// Output formats of an export
+const (
+	FormatDynamoDBJSON = "DYNAMODB_JSON"
+	FormatION          = "ION"
+)
+
+// Files reads the files of an export
+type Files interface {
+	// Open opens a file by its name relative to the export's directory, such as "manifest-summary.json"
+	Open(name string) (io.ReadCloser, error)
+}
+
+// Open creates the Files of the export at a URL, which is the directory holding its manifests:
+//   - file:///path/to/export, or a path, reads a local copy of the export
+//   - s3://bucket/prefix/AWSDynamoDB/01234567890123-abcdefgh reads the export in S3
+//
+// endpoint optionally overrides the AWS endpoint, for S3 compatible services.
+func Open(rawURL, endpoint string) (Files, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid export URL %s: %s", rawURL, err)
+	}
+
+	switch u.Scheme {
+	case "", "file":
+		if u.Path == "" {
+			return nil, fmt.Errorf("export URL %s is missing a directory", rawURL)
+		}
+		return DirFiles(u.Path), nil
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("export URL %s is missing a bucket", rawURL)
+		}
+		config := aws.NewConfig()
+		if endpoint != "" {
+			config = config.WithEndpoint(endpoint).WithS3ForcePathStyle(true)
+		}
+		client := s3.New(session.Must(session.NewSession()), config)
+		return NewS3Files(client, u.Host, strings.TrimPrefix(u.Path, "/")), nil
+	default:
+		return nil, fmt.Errorf("unsupported export URL scheme %s", u.Scheme)
+	}
+}
+
+// DirFiles reads an export copied to a local directory
+type DirFiles string
+
+// Open implements Files
+func (d DirFiles) Open(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(d), filepath.FromSlash(name)))
+}
+
+// S3Files reads an export in an S3 bucket
+type S3Files struct {
+	client s3iface.S3API
+	bucket string
+	prefix string
+}
+
+// NewS3Files creates S3Files reading the export under prefix in bucket
+func NewS3Files(client s3iface.S3API, bucket, prefix string) *S3Files {
+	return &S3Files{client: client, bucket: bucket, prefix: prefix}
+}
+
+// Open implements Files
+func (s *S3Files) Open(name string) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path.Join(s.prefix, name)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+// Manifest describes an export
+type Manifest struct {
+	ExportTime   time.Time `json:"exportTime"`
+	OutputFormat string    `json:"outputFormat"`
+	ItemCount    int       `json:"itemCount"`
+	// DataFiles lists the files holding the exported items
+	DataFiles []DataFile `json:"-"`
+}
+
+// DataFile is a gzip'd file of exported items
+type DataFile struct {
+	// Name relative to the export's directory, such as "data/abcdefgh.json.gz"
+	Name      string
+	ItemCount int
+}
+
+// ReadManifest reads the manifest-summary.json and manifest-files.json of an export
+func ReadManifest(files Files) (Manifest, error) {
+	manifest := Manifest{}
+	summary, err := files.Open("manifest-summary.json")
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer summary.Close()
+	if err := json.NewDecoder(summary).Decode(&manifest); err != nil {
+		return Manifest{}, fmt.Errorf("invalid manifest-summary.json: %s", err)
+	}
+	switch manifest.OutputFormat {
+	case FormatDynamoDBJSON, FormatION:
+	default:
+		return Manifest{}, fmt.Errorf("unsupported export format %q", manifest.OutputFormat)
+	}
+
+	dataFiles, err := files.Open("manifest-files.json")
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer dataFiles.Close()
+	scanner := bufio.NewScanner(dataFiles)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		dataFile := struct {
+			ItemCount     int    `json:"itemCount"`
+			DataFileS3Key string `json:"dataFileS3Key"`
+		}{}
+		if err := json.Unmarshal(scanner.Bytes(), &dataFile); err != nil {
+			return Manifest{}, fmt.Errorf("manifest-files.json line %d: %s", line, err)
+		}
+		// keys are of the bucket the export was written to, while the export may have been copied elsewhere
+		manifest.DataFiles = append(manifest.DataFiles, DataFile{
+			Name:      "data/" + path.Base(dataFile.DataFileS3Key),
+			ItemCount: dataFile.ItemCount,
+		})
+	}
+	return manifest, scanner.Err()
+}
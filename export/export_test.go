@@ -0,0 +1,114 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeExport writes an export with a data file per entry of data to a temporary directory
+func writeExport(t *testing.T, format string, data ...string) string {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "data"), 0755))
+	summary := `{"exportTime":"2024-07-16T15:00:00Z","outputFormat":"` + format + `","itemCount":3}`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "manifest-summary.json"), []byte(summary), 0644))
+
+	manifest := &strings.Builder{}
+	for i, d := range data {
+		name := string(rune('a'+i)) + ".json.gz"
+		manifest.WriteString(`{"itemCount":1,"md5Checksum":"x","etag":"y","dataFileS3Key":"exports/AWSDynamoDB/01234567890123-abcdefgh/data/` + name + `"}` + "\n")
+		buf := &bytes.Buffer{}
+		gz := gzip.NewWriter(buf)
+		gz.Write([]byte(d))
+		require.NoError(t, gz.Close())
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "data", name), buf.Bytes(), 0644))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "manifest-files.json"), []byte(manifest.String()), 0644))
+	return dir
+}
+
+// decodeAll returns every item of a data file
+func decodeAll(t *testing.T, files Files, name, format string) []map[string]events.DynamoDBAttributeValue {
+	f, err := files.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+	decoder, err := NewDecoder(f, format)
+	require.NoError(t, err)
+	items := []map[string]events.DynamoDBAttributeValue{}
+	for {
+		item, err := decoder.Decode()
+		if err == io.EOF {
+			return items
+		}
+		require.NoError(t, err)
+		items = append(items, item)
+	}
+}
+
+func TestReadManifest(t *testing.T) {
+	dir := writeExport(t, FormatDynamoDBJSON, "", "")
+	files, err := Open("file://"+dir, "")
+	require.NoError(t, err)
+
+	manifest, err := ReadManifest(files)
+	require.NoError(t, err)
+	assert.Equal(t, Manifest{
+		ExportTime:   time.Date(2024, 7, 16, 15, 0, 0, 0, time.UTC),
+		OutputFormat: FormatDynamoDBJSON,
+		ItemCount:    3,
+		DataFiles:    []DataFile{{Name: "data/a.json.gz", ItemCount: 1}, {Name: "data/b.json.gz", ItemCount: 1}},
+	}, manifest)
+
+	_, err = ReadManifest(DirFiles(writeExport(t, "CSV")))
+	assert.EqualError(t, err, `unsupported export format "CSV"`)
+}
+
+func TestOpen(t *testing.T) {
+	files, err := Open("/tmp/export", "")
+	require.NoError(t, err)
+	assert.Equal(t, DirFiles("/tmp/export"), files)
+
+	files, err = Open("s3://bucket/exports/AWSDynamoDB/01234567890123-abcdefgh", "http://localhost:9000")
+	require.NoError(t, err)
+	assert.Equal(t, "exports/AWSDynamoDB/01234567890123-abcdefgh", files.(*S3Files).prefix)
+
+	_, err = Open("s3:///prefix", "")
+	assert.EqualError(t, err, "export URL s3:///prefix is missing a bucket")
+	_, err = Open("ftp://host/export", "")
+	assert.EqualError(t, err, "unsupported export URL scheme ftp")
+}
+
+func TestDecodeDynamoDBJSON(t *testing.T) {
+	dir := writeExport(t, FormatDynamoDBJSON,
+		`{"Item":{"id":{"S":"1"},"n":{"N":"1.5"},"ss":{"SS":["a","b"]},"m":{"M":{"b":{"BOOL":true}}}}}
+{"Item":{"id":{"S":"2"},"b":{"B":"aGk="},"l":{"L":[{"NULL":true}]}}}
+`)
+	assert.Equal(t, []map[string]events.DynamoDBAttributeValue{
+		{
+			"id": events.NewStringAttribute("1"),
+			"n":  events.NewNumberAttribute("1.5"),
+			"ss": events.NewStringSetAttribute([]string{"a", "b"}),
+			"m":  events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{"b": events.NewBooleanAttribute(true)}),
+		},
+		{
+			"id": events.NewStringAttribute("2"),
+			"b":  events.NewBinaryAttribute([]byte("hi")),
+			"l":  events.NewListAttribute([]events.DynamoDBAttributeValue{events.NewNullAttribute()}),
+		},
+	}, decodeAll(t, DirFiles(dir), "data/a.json.gz", FormatDynamoDBJSON))
+
+	decoder, err := NewDecoder(strings.NewReader(`{"id":{"S":"1"}}`), FormatDynamoDBJSON)
+	require.NoError(t, err)
+	_, err = decoder.Decode()
+	assert.EqualError(t, err, "item 1: missing Item")
+}
@@ -0,0 +1,472 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ionDecoder reads Amazon Ion text, a struct per item such as {Item:{id:"1"}}.
+// It supports the subset of Ion that DynamoDB exports: structs, lists, strings, symbols, numbers,
+// blobs, booleans and nulls, with the $dynamodb_SS, $dynamodb_NS and $dynamodb_BS annotations
+// marking sets.
+type ionDecoder struct {
+	r    *bufio.Reader
+	item int
+}
+
+// ionVersionMarker may start an Ion stream, and is not a value
+const ionVersionMarker = "$ion_1_0"
+
+// ionDecimal matches the decimal numbers DynamoDB supports, once Ion's d exponent is replaced
+var ionDecimal = regexp.MustCompile(`^-?[0-9]+(\.[0-9]*)?([eE][+-]?[0-9]+)?$`)
+
+func (d *ionDecoder) Decode() (map[string]events.DynamoDBAttributeValue, error) {
+	for {
+		if err := d.skipSpace(); err != nil {
+			return nil, err
+		}
+		value, symbol, err := d.value()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %s", d.item+1, err)
+		}
+		if symbol && value.String() == ionVersionMarker {
+			continue
+		}
+		d.item++
+		if value.DataType() != events.DataTypeMap {
+			return nil, fmt.Errorf("item %d: expected a struct", d.item)
+		}
+		item, ok := value.Map()["Item"]
+		if !ok || item.DataType() != events.DataTypeMap {
+			return nil, fmt.Errorf("item %d: missing Item", d.item)
+		}
+		return item.Map(), nil
+	}
+}
+
+// value reads a value and its annotations. symbol is true if it's a symbol rather than a string.
+func (d *ionDecoder) value() (value events.DynamoDBAttributeValue, symbol bool, err error) {
+	annotations := []string{}
+	for {
+		if err := d.skipSpace(); err != nil {
+			return value, false, err
+		}
+		start, err := d.peek(3)
+		if err != nil && len(start) == 0 {
+			return value, false, err
+		}
+
+		switch {
+		case bytes.HasPrefix(start, []byte("{{")):
+			b, err := d.blob()
+			return events.NewBinaryAttribute(b), false, err
+		case start[0] == '{':
+			value, err = d.structValue()
+			return value, false, err
+		case start[0] == '[':
+			value, err = d.list(annotations)
+			return value, false, err
+		case start[0] == '"' || bytes.Equal(start, []byte("'''")):
+			s, err := d.stringValue()
+			return events.NewStringAttribute(s), false, err
+		}
+
+		var token string
+		if start[0] == '\'' {
+			token, err = d.quoted('\'')
+		} else {
+			token, err = d.token()
+		}
+		if err != nil {
+			return value, false, err
+		}
+		annotation, err := d.consume("::")
+		if err != nil {
+			return value, false, err
+		}
+		if annotation {
+			annotations = append(annotations, token)
+			continue
+		}
+		if start[0] == '\'' {
+			return events.NewStringAttribute(token), true, nil
+		}
+		return d.scalar(token)
+	}
+}
+
+// scalar converts an unquoted token: a boolean, null, number or symbol
+func (d *ionDecoder) scalar(token string) (events.DynamoDBAttributeValue, bool, error) {
+	switch {
+	case token == "true" || token == "false":
+		return events.NewBooleanAttribute(token == "true"), false, nil
+	case token == "null" || strings.HasPrefix(token, "null."):
+		return events.NewNullAttribute(), false, nil
+	case token[0] == '-' || token[0] == '+' || (token[0] >= '0' && token[0] <= '9'):
+		number, err := ionNumber(token)
+		return events.NewNumberAttribute(number), false, err
+	default:
+		return events.NewStringAttribute(token), true, nil
+	}
+}
+
+// ionNumber converts an Ion int or decimal to a DynamoDB number, such as "1.5e-3" for 1.5d-3
+func ionNumber(token string) (string, error) {
+	number := strings.ReplaceAll(token, "_", "")
+	if i, err := strconv.ParseInt(number, 0, 64); err == nil {
+		return strconv.FormatInt(i, 10), nil
+	}
+	number = strings.NewReplacer("d", "e", "D", "e").Replace(number)
+	mantissa, exponent := number, ""
+	if i := strings.IndexAny(number, "eE"); i >= 0 {
+		mantissa, exponent = number[:i], number[i:]
+	}
+	number = strings.TrimSuffix(mantissa, ".") + exponent
+	if !ionDecimal.MatchString(number) {
+		return "", fmt.Errorf("unsupported number %s", token)
+	}
+	return number, nil
+}
+
+// structValue reads a struct as a map
+func (d *ionDecoder) structValue() (events.DynamoDBAttributeValue, error) {
+	d.r.ReadByte()
+	fields := map[string]events.DynamoDBAttributeValue{}
+	for {
+		if err := d.skipSpace(); err != nil {
+			return events.DynamoDBAttributeValue{}, err
+		}
+		if end, err := d.consume("}"); err != nil || end {
+			return events.NewMapAttribute(fields), err
+		}
+
+		start, err := d.peek(3)
+		if err != nil && len(start) == 0 {
+			return events.DynamoDBAttributeValue{}, err
+		}
+		var name string
+		switch {
+		case start[0] == '"' || bytes.Equal(start, []byte("'''")):
+			name, err = d.stringValue()
+		case start[0] == '\'':
+			name, err = d.quoted('\'')
+		default:
+			name, err = d.token()
+		}
+		if err != nil {
+			return events.DynamoDBAttributeValue{}, err
+		}
+		if err := d.expect(":"); err != nil {
+			return events.DynamoDBAttributeValue{}, err
+		}
+		value, _, err := d.value()
+		if err != nil {
+			return events.DynamoDBAttributeValue{}, err
+		}
+		fields[name] = value
+
+		if err := d.skipSpace(); err != nil {
+			return events.DynamoDBAttributeValue{}, err
+		}
+		if end, err := d.consume("}"); err != nil || end {
+			return events.NewMapAttribute(fields), err
+		}
+		if err := d.expect(","); err != nil {
+			return events.DynamoDBAttributeValue{}, err
+		}
+	}
+}
+
+// list reads a list, or a set if it's annotated as one
+func (d *ionDecoder) list(annotations []string) (events.DynamoDBAttributeValue, error) {
+	d.r.ReadByte()
+	values := []events.DynamoDBAttributeValue{}
+	for {
+		if err := d.skipSpace(); err != nil {
+			return events.DynamoDBAttributeValue{}, err
+		}
+		if end, err := d.consume("]"); err != nil {
+			return events.DynamoDBAttributeValue{}, err
+		} else if end {
+			break
+		}
+		value, _, err := d.value()
+		if err != nil {
+			return events.DynamoDBAttributeValue{}, err
+		}
+		values = append(values, value)
+
+		if err := d.skipSpace(); err != nil {
+			return events.DynamoDBAttributeValue{}, err
+		}
+		if end, err := d.consume("]"); err != nil {
+			return events.DynamoDBAttributeValue{}, err
+		} else if end {
+			break
+		}
+		if err := d.expect(","); err != nil {
+			return events.DynamoDBAttributeValue{}, err
+		}
+	}
+
+	set := ""
+	for _, annotation := range annotations {
+		switch annotation {
+		case "$dynamodb_SS", "$dynamodb_NS", "$dynamodb_BS":
+			set = annotation
+		}
+	}
+	switch set {
+	case "$dynamodb_SS", "$dynamodb_NS":
+		members := []string{}
+		for _, value := range values {
+			if set == "$dynamodb_SS" && value.DataType() == events.DataTypeString {
+				members = append(members, value.String())
+			} else if set == "$dynamodb_NS" && value.DataType() == events.DataTypeNumber {
+				members = append(members, value.Number())
+			} else {
+				return events.DynamoDBAttributeValue{}, fmt.Errorf("invalid member of %s set", set)
+			}
+		}
+		if set == "$dynamodb_SS" {
+			return events.NewStringSetAttribute(members), nil
+		}
+		return events.NewNumberSetAttribute(members), nil
+	case "$dynamodb_BS":
+		members := [][]byte{}
+		for _, value := range values {
+			if value.DataType() != events.DataTypeBinary {
+				return events.DynamoDBAttributeValue{}, fmt.Errorf("invalid member of %s set", set)
+			}
+			members = append(members, value.Binary())
+		}
+		return events.NewBinarySetAttribute(members), nil
+	}
+	return events.NewListAttribute(values), nil
+}
+
+// blob reads a base64 blob, or a clob, between {{ and }}
+func (d *ionDecoder) blob() ([]byte, error) {
+	d.r.Discard(2)
+	if err := d.skipSpace(); err != nil {
+		return nil, err
+	}
+	start, _ := d.peek(3)
+	if len(start) > 0 && (start[0] == '"' || bytes.Equal(start, []byte("'''"))) {
+		s, err := d.stringValue()
+		if err != nil {
+			return nil, err
+		}
+		return []byte(s), d.expect("}}")
+	}
+
+	encoded := &strings.Builder{}
+	for {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if c == '}' {
+			d.r.UnreadByte()
+			break
+		}
+		if !isSpace(c) {
+			encoded.WriteByte(c)
+		}
+	}
+	if err := d.expect("}}"); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(encoded.String())
+}
+
+// stringValue reads a quoted string, or adjacent long strings which are concatenated
+func (d *ionDecoder) stringValue() (string, error) {
+	if long, err := d.consume("'''"); err != nil || !long {
+		if err != nil {
+			return "", err
+		}
+		return d.quoted('"')
+	}
+	s := &strings.Builder{}
+	for {
+		part, err := d.until("'''")
+		if err != nil {
+			return "", err
+		}
+		s.WriteString(part)
+		if err := d.skipSpace(); err != nil && err != io.EOF {
+			return "", err
+		}
+		if more, err := d.consume("'''"); err != nil || !more {
+			return s.String(), err
+		}
+	}
+}
+
+// quoted reads a string or symbol quoted with quote, unescaping it
+func (d *ionDecoder) quoted(quote byte) (string, error) {
+	d.r.ReadByte()
+	return d.until(string(quote))
+}
+
+// until reads and unescapes text up to the unescaped end, consuming it
+func (d *ionDecoder) until(end string) (string, error) {
+	s := &strings.Builder{}
+	for {
+		if done, err := d.consume(end); err != nil || done {
+			return s.String(), err
+		}
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if c != '\\' {
+			s.WriteByte(c)
+			continue
+		}
+		if err := d.unescape(s); err != nil {
+			return "", err
+		}
+	}
+}
+
+// unescape reads the escape sequence following a backslash
+func (d *ionDecoder) unescape(s *strings.Builder) error {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if unescaped, ok := ionEscapes[c]; ok {
+		s.WriteString(unescaped)
+		return nil
+	}
+	digits := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+	if digits == 0 {
+		return fmt.Errorf("invalid escape \\%c", c)
+	}
+	hex := make([]byte, digits)
+	if _, err := io.ReadFull(d.r, hex); err != nil {
+		return err
+	}
+	code, err := strconv.ParseUint(string(hex), 16, 32)
+	if err != nil || code > utf8.MaxRune {
+		return fmt.Errorf("invalid escape \\%c%s", c, hex)
+	}
+	s.WriteRune(rune(code))
+	return nil
+}
+
+// ionEscapes maps the characters of escape sequences to what they stand for. An escaped newline
+// continues a string on the next line.
+var ionEscapes = map[byte]string{
+	'a': "\a", 'b': "\b", 't': "\t", 'n': "\n", 'f': "\f", 'r': "\r", 'v': "\v",
+	'?': "?", '0': "\x00", '\'': "'", '"': "\"", '/': "/", '\\': "\\", '\n': "",
+}
+
+// token reads an unquoted symbol or number
+func (d *ionDecoder) token() (string, error) {
+	token := &strings.Builder{}
+	for {
+		c, err := d.r.ReadByte()
+		if err == io.EOF && token.Len() > 0 {
+			return token.String(), nil
+		} else if err != nil {
+			return "", err
+		}
+		if !isTokenChar(c) {
+			d.r.UnreadByte()
+			if token.Len() == 0 {
+				return "", fmt.Errorf("unexpected %q", c)
+			}
+			return token.String(), nil
+		}
+		token.WriteByte(c)
+	}
+}
+
+// skipSpace skips whitespace and comments
+func (d *ionDecoder) skipSpace() error {
+	for {
+		next, err := d.peek(2)
+		if err != nil {
+			return err
+		}
+		switch {
+		case isSpace(next[0]):
+			d.r.ReadByte()
+		case bytes.HasPrefix(next, []byte("//")):
+			if _, err := d.r.ReadString('\n'); err != nil && err != io.EOF {
+				return err
+			}
+		case bytes.HasPrefix(next, []byte("/*")):
+			d.r.Discard(2)
+			if _, err := d.until("*/"); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// peek returns up to the next n bytes without consuming them
+func (d *ionDecoder) peek(n int) ([]byte, error) {
+	b, err := d.r.Peek(n)
+	if err == io.EOF && len(b) > 0 {
+		err = nil
+	}
+	return b, err
+}
+
+// consume consumes s if it's next, returning true if it was
+func (d *ionDecoder) consume(s string) (bool, error) {
+	next, err := d.peek(len(s))
+	if err == io.EOF {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if string(next) != s {
+		return false, nil
+	}
+	_, err = d.r.Discard(len(s))
+	return true, err
+}
+
+// expect consumes s, which must be next after any whitespace
+func (d *ionDecoder) expect(s string) error {
+	if err := d.skipSpace(); err != nil {
+		return err
+	}
+	ok, err := d.consume(s)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		next, _ := d.peek(1)
+		return fmt.Errorf("expected %q, found %q", s, next)
+	}
+	return nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+func isTokenChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '$' || c == '.' || c == '+' || c == '-'
+}
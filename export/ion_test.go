@@ -0,0 +1,70 @@
+package export
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeION(t *testing.T) {
+	dir := writeExport(t, FormatION, `$ion_1_0 {Item:{id:"1",n:123.,f:1.5d-3,big:12345678901234567890123456789,b:{{aGk=}},t:true,nul:null}}
+{Item:{id:"2",'quoted field':'symbol',"string field":"a\"bé",ss:$dynamodb_SS::["a","b"],ns:$dynamodb_NS::[1,2.50],bs:$dynamodb_BS::[{{aGk=}}]}}
+// a comment
+{Item:{id:"3",l:[1, "a", {m:[]}], long:'''con''' '''cat'''}}
+`)
+	assert.Equal(t, []map[string]events.DynamoDBAttributeValue{
+		{
+			"id":  events.NewStringAttribute("1"),
+			"n":   events.NewNumberAttribute("123"),
+			"f":   events.NewNumberAttribute("1.5e-3"),
+			"big": events.NewNumberAttribute("12345678901234567890123456789"),
+			"b":   events.NewBinaryAttribute([]byte("hi")),
+			"t":   events.NewBooleanAttribute(true),
+			"nul": events.NewNullAttribute(),
+		},
+		{
+			"id":           events.NewStringAttribute("2"),
+			"quoted field": events.NewStringAttribute("symbol"),
+			"string field": events.NewStringAttribute(`a"bé`),
+			"ss":           events.NewStringSetAttribute([]string{"a", "b"}),
+			"ns":           events.NewNumberSetAttribute([]string{"1", "2.50"}),
+			"bs":           events.NewBinarySetAttribute([][]byte{[]byte("hi")}),
+		},
+		{
+			"id": events.NewStringAttribute("3"),
+			"l": events.NewListAttribute([]events.DynamoDBAttributeValue{
+				events.NewNumberAttribute("1"),
+				events.NewStringAttribute("a"),
+				events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{
+					"m": events.NewListAttribute([]events.DynamoDBAttributeValue{}),
+				}),
+			}),
+			"long": events.NewStringAttribute("concat"),
+		},
+	}, decodeAll(t, DirFiles(dir), "data/a.json.gz", FormatION))
+}
+
+func TestDecodeIONErrors(t *testing.T) {
+	for input, expected := range map[string]string{
+		`{Item:{id:"1"}`:               "item 1: unexpected EOF",
+		`{Item:{id:"1" n:1}}`:          `item 1: expected ",", found "n"`,
+		`{Item:{n:1.5.5}}`:             "item 1: unsupported number 1.5.5",
+		`{Item:{s:$dynamodb_SS::[1]}}`: "item 1: invalid member of $dynamodb_SS set",
+		`{Other:{}}`:                   "item 1: missing Item",
+		`"text"`:                       "item 1: expected a struct",
+	} {
+		decoder, err := NewDecoder(strings.NewReader(input), FormatION)
+		require.NoError(t, err)
+		_, err = decoder.Decode()
+		assert.EqualError(t, err, expected, input)
+	}
+
+	decoder, err := NewDecoder(strings.NewReader("$ion_1_0\n"), FormatION)
+	require.NoError(t, err)
+	_, err = decoder.Decode()
+	assert.Equal(t, io.EOF, err)
+}
@@ -3,18 +3,18 @@ module github.com/Clever/ddb-to-es
 go 1.16
 
 require (
-	github.com/aws/aws-lambda-go v1.24.0
+	github.com/aws/aws-lambda-go v1.34.1
+	github.com/aws/aws-sdk-go v1.44.100
 	github.com/fortytw2/leaktest v1.3.0 // indirect
 	github.com/google/go-cmp v0.5.6 // indirect
 	github.com/kevinburke/go-bindata v3.22.0+incompatible
 	github.com/mailru/easyjson v0.0.0-20171120080333-32fa128f234d // indirect
 	github.com/olivere/elastic v6.1.4+incompatible // indirect
-	github.com/pkg/errors v0.8.0 // indirect
-	github.com/stretchr/testify v1.7.1-0.20210427113832-6241f9ab9942
+	github.com/stretchr/testify v1.7.2
 	github.com/xeipuuv/gojsonpointer v0.0.0-20170225233418-6fe8760cad35 // indirect
 	github.com/xeipuuv/gojsonreference v0.0.0-20150808065054-e02fc20de94c // indirect
 	github.com/xeipuuv/gojsonschema v0.0.0-20171230112544-511d08a359d1 // indirect
 	gopkg.in/Clever/kayvee-go.v6 v6.26.0
 	gopkg.in/olivere/elastic.v6 v6.2.19
-	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
+	gopkg.in/yaml.v2 v2.2.8
 )